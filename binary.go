@@ -0,0 +1,365 @@
+package fwd
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// fixed returns the next 'n' bytes in the
+// stream and advances the reader, like Next
+func (r *Reader) fixed(n int) ([]byte, error) {
+	if len(r.data)-r.n >= n {
+		b := r.data[r.n : r.n+n]
+		r.n += n
+		r.inputOffset += int64(n)
+		return b, nil
+	}
+	return r.next(n)
+}
+
+// fixed returns the next 'n' free bytes in the
+// write buffer and advances the write position,
+// like Next
+func (w *Writer) fixed(n int) ([]byte, error) {
+	if l := len(w.buf); cap(w.buf)-l >= n && w.err == nil {
+		w.buf = w.buf[:l+n]
+		return w.buf[l:], nil
+	}
+	return w.Next(n)
+}
+
+// ReadUint16BE reads a big-endian uint16.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 2 bytes are read.
+func (r *Reader) ReadUint16BE() (uint16, error) {
+	b, err := r.fixed(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// ReadUint16LE reads a little-endian uint16.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 2 bytes are read.
+func (r *Reader) ReadUint16LE() (uint16, error) {
+	b, err := r.fixed(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// ReadUint32BE reads a big-endian uint32.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 4 bytes are read.
+func (r *Reader) ReadUint32BE() (uint32, error) {
+	b, err := r.fixed(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// ReadUint32LE reads a little-endian uint32.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 4 bytes are read.
+func (r *Reader) ReadUint32LE() (uint32, error) {
+	b, err := r.fixed(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// ReadUint64BE reads a big-endian uint64.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 8 bytes are read.
+func (r *Reader) ReadUint64BE() (uint64, error) {
+	b, err := r.fixed(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// ReadUint64LE reads a little-endian uint64.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 8 bytes are read.
+func (r *Reader) ReadUint64LE() (uint64, error) {
+	b, err := r.fixed(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// ReadInt16BE reads a big-endian int16.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 2 bytes are read.
+func (r *Reader) ReadInt16BE() (int16, error) {
+	b, err := r.fixed(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+// ReadInt16LE reads a little-endian int16.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 2 bytes are read.
+func (r *Reader) ReadInt16LE() (int16, error) {
+	b, err := r.fixed(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.LittleEndian.Uint16(b)), nil
+}
+
+// ReadInt32BE reads a big-endian int32.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 4 bytes are read.
+func (r *Reader) ReadInt32BE() (int32, error) {
+	b, err := r.fixed(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+// ReadInt32LE reads a little-endian int32.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 4 bytes are read.
+func (r *Reader) ReadInt32LE() (int32, error) {
+	b, err := r.fixed(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(b)), nil
+}
+
+// ReadInt64BE reads a big-endian int64.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 8 bytes are read.
+func (r *Reader) ReadInt64BE() (int64, error) {
+	b, err := r.fixed(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// ReadInt64LE reads a little-endian int64.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 8 bytes are read.
+func (r *Reader) ReadInt64LE() (int64, error) {
+	b, err := r.fixed(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(b)), nil
+}
+
+// ReadFloat32BE reads a big-endian float32.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 4 bytes are read.
+func (r *Reader) ReadFloat32BE() (float32, error) {
+	b, err := r.fixed(4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.BigEndian.Uint32(b)), nil
+}
+
+// ReadFloat32LE reads a little-endian float32.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 4 bytes are read.
+func (r *Reader) ReadFloat32LE() (float32, error) {
+	b, err := r.fixed(4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+}
+
+// ReadFloat64BE reads a big-endian float64.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 8 bytes are read.
+func (r *Reader) ReadFloat64BE() (float64, error) {
+	b, err := r.fixed(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+// ReadFloat64LE reads a little-endian float64.
+// Like [Reader.Next], it returns [io.ErrUnexpectedEOF]
+// if the stream ends before 8 bytes are read.
+func (r *Reader) ReadFloat64LE() (float64, error) {
+	b, err := r.fixed(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+}
+
+// WriteUint16BE writes 'v' as a big-endian uint16.
+func (w *Writer) WriteUint16BE(v uint16) error {
+	b, err := w.fixed(2)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(b, v)
+	return nil
+}
+
+// WriteUint16LE writes 'v' as a little-endian uint16.
+func (w *Writer) WriteUint16LE(v uint16) error {
+	b, err := w.fixed(2)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint16(b, v)
+	return nil
+}
+
+// WriteUint32BE writes 'v' as a big-endian uint32.
+func (w *Writer) WriteUint32BE(v uint32) error {
+	b, err := w.fixed(4)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(b, v)
+	return nil
+}
+
+// WriteUint32LE writes 'v' as a little-endian uint32.
+func (w *Writer) WriteUint32LE(v uint32) error {
+	b, err := w.fixed(4)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b, v)
+	return nil
+}
+
+// WriteUint64BE writes 'v' as a big-endian uint64.
+func (w *Writer) WriteUint64BE(v uint64) error {
+	b, err := w.fixed(8)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint64(b, v)
+	return nil
+}
+
+// WriteUint64LE writes 'v' as a little-endian uint64.
+func (w *Writer) WriteUint64LE(v uint64) error {
+	b, err := w.fixed(8)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint64(b, v)
+	return nil
+}
+
+// WriteInt16BE writes 'v' as a big-endian int16.
+func (w *Writer) WriteInt16BE(v int16) error {
+	b, err := w.fixed(2)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(b, uint16(v))
+	return nil
+}
+
+// WriteInt16LE writes 'v' as a little-endian int16.
+func (w *Writer) WriteInt16LE(v int16) error {
+	b, err := w.fixed(2)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint16(b, uint16(v))
+	return nil
+}
+
+// WriteInt32BE writes 'v' as a big-endian int32.
+func (w *Writer) WriteInt32BE(v int32) error {
+	b, err := w.fixed(4)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(b, uint32(v))
+	return nil
+}
+
+// WriteInt32LE writes 'v' as a little-endian int32.
+func (w *Writer) WriteInt32LE(v int32) error {
+	b, err := w.fixed(4)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b, uint32(v))
+	return nil
+}
+
+// WriteInt64BE writes 'v' as a big-endian int64.
+func (w *Writer) WriteInt64BE(v int64) error {
+	b, err := w.fixed(8)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return nil
+}
+
+// WriteInt64LE writes 'v' as a little-endian int64.
+func (w *Writer) WriteInt64LE(v int64) error {
+	b, err := w.fixed(8)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint64(b, uint64(v))
+	return nil
+}
+
+// WriteFloat32BE writes 'v' as a big-endian float32.
+func (w *Writer) WriteFloat32BE(v float32) error {
+	b, err := w.fixed(4)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(b, math.Float32bits(v))
+	return nil
+}
+
+// WriteFloat32LE writes 'v' as a little-endian float32.
+func (w *Writer) WriteFloat32LE(v float32) error {
+	b, err := w.fixed(4)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b, math.Float32bits(v))
+	return nil
+}
+
+// WriteFloat64BE writes 'v' as a big-endian float64.
+func (w *Writer) WriteFloat64BE(v float64) error {
+	b, err := w.fixed(8)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return nil
+}
+
+// WriteFloat64LE writes 'v' as a little-endian float64.
+func (w *Writer) WriteFloat64LE(v float64) error {
+	b, err := w.fixed(8)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	return nil
+}
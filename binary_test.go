@@ -0,0 +1,123 @@
+package fwd
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestBinary(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 16)
+
+	const count = 100
+	for i := 0; i < count; i++ {
+		u := uint64(i) * 0x0102030405060708
+		wr.WriteUint16BE(uint16(u))
+		wr.WriteUint16LE(uint16(u))
+		wr.WriteUint32BE(uint32(u))
+		wr.WriteUint32LE(uint32(u))
+		wr.WriteUint64BE(u)
+		wr.WriteUint64LE(u)
+		wr.WriteInt16BE(-int16(u))
+		wr.WriteInt16LE(-int16(u))
+		wr.WriteInt32BE(-int32(u))
+		wr.WriteInt32LE(-int32(u))
+		wr.WriteInt64BE(-int64(u))
+		wr.WriteInt64LE(-int64(u))
+		wr.WriteFloat32BE(float32(i) / 3)
+		wr.WriteFloat32LE(float32(i) / 3)
+		wr.WriteFloat64BE(math.Pi * float64(i))
+		if err := wr.WriteFloat64LE(math.Pi * float64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != count*(2*(2+4+8)*2+2*(4+8)) {
+		t.Fatalf("wrote %d bytes", buf.Len())
+	}
+
+	rd := NewReaderSize(partialReader{bytes.NewReader(buf.Bytes())}, 16)
+	check := func(i int, what string, ok bool, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%d: %s: %s", i, what, err)
+		}
+		if !ok {
+			t.Fatalf("%d: %s: values not equal", i, what)
+		}
+	}
+	for i := 0; i < count; i++ {
+		u := uint64(i) * 0x0102030405060708
+		u16, err := rd.ReadUint16BE()
+		check(i, "uint16 BE", u16 == uint16(u), err)
+		u16, err = rd.ReadUint16LE()
+		check(i, "uint16 LE", u16 == uint16(u), err)
+		u32, err := rd.ReadUint32BE()
+		check(i, "uint32 BE", u32 == uint32(u), err)
+		u32, err = rd.ReadUint32LE()
+		check(i, "uint32 LE", u32 == uint32(u), err)
+		u64, err := rd.ReadUint64BE()
+		check(i, "uint64 BE", u64 == u, err)
+		u64, err = rd.ReadUint64LE()
+		check(i, "uint64 LE", u64 == u, err)
+		i16, err := rd.ReadInt16BE()
+		check(i, "int16 BE", i16 == -int16(u), err)
+		i16, err = rd.ReadInt16LE()
+		check(i, "int16 LE", i16 == -int16(u), err)
+		i32, err := rd.ReadInt32BE()
+		check(i, "int32 BE", i32 == -int32(u), err)
+		i32, err = rd.ReadInt32LE()
+		check(i, "int32 LE", i32 == -int32(u), err)
+		i64, err := rd.ReadInt64BE()
+		check(i, "int64 BE", i64 == -int64(u), err)
+		i64, err = rd.ReadInt64LE()
+		check(i, "int64 LE", i64 == -int64(u), err)
+		f32, err := rd.ReadFloat32BE()
+		check(i, "float32 BE", f32 == float32(i)/3, err)
+		f32, err = rd.ReadFloat32LE()
+		check(i, "float32 LE", f32 == float32(i)/3, err)
+		f64, err := rd.ReadFloat64BE()
+		check(i, "float64 BE", f64 == math.Pi*float64(i), err)
+		f64, err = rd.ReadFloat64LE()
+		check(i, "float64 LE", f64 == math.Pi*float64(i), err)
+	}
+
+	// the stream ends partway through a value
+	rd = NewReaderBytes([]byte{1, 2, 3})
+	if _, err := rd.ReadUint32BE(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+	}
+	if rd.InputOffset() != 0 {
+		t.Fatalf("expected offset 0; got %d", rd.InputOffset())
+	}
+}
+
+func TestBinaryAllocs(t *testing.T) {
+	rd := NewReaderBytes(make([]byte, 1<<16))
+	wr := NewWriter(io.Discard)
+	allocs := testing.AllocsPerRun(100, func() {
+		v, _ := rd.ReadUint64LE()
+		f, _ := rd.ReadFloat32BE()
+		wr.WriteUint64LE(v)
+		wr.WriteFloat32BE(f)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected 0 allocations; got %v", allocs)
+	}
+}
+
+func BenchmarkReadUint32BE(b *testing.B) {
+	data := randomBts(4096)
+	rd := NewReaderBytes(data)
+	b.SetBytes(4)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := rd.ReadUint32BE(); err != nil {
+			rd.Seek(0, io.SeekStart)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package fwd
+
+import (
+	"errors"
+	"io"
+)
+
+// BitOrder determines the order in which
+// bits are packed into each byte.
+type BitOrder int
+
+const (
+	// MSBFirst packs bits starting with the most
+	// significant bit of each byte (as in, e.g., JPEG)
+	MSBFirst BitOrder = iota
+	// LSBFirst packs bits starting with the least
+	// significant bit of each byte (as in, e.g., DEFLATE)
+	LSBFirst
+)
+
+// ErrTooManyBits is returned when more
+// than 64 bits (or 57 bits, for PeekBits)
+// are requested at once.
+var ErrTooManyBits = errors.New("fwd: too many bits requested")
+
+// maxPeekBits is the largest number of bits that
+// always fit in 64 bits along with a partial byte
+const maxPeekBits = 57
+
+// BitReader reads individual bits from a [Reader].
+// The BitReader only takes bytes from the Reader as
+// their bits are consumed, so bytes are only ever
+// peeked ahead of the bit position, and the Reader can
+// be used directly again after [BitReader.AlignToByte].
+type BitReader struct {
+	r     *Reader
+	order BitOrder
+	acc   uint64 // unread bits of the last byte taken from r
+	nbits uint   // number of bits in acc; always less than 8
+}
+
+// NewBitReader returns a BitReader that
+// reads from 'r' with bits packed in 'order'.
+func NewBitReader(r *Reader, order BitOrder) *BitReader {
+	return &BitReader{r: r, order: order}
+}
+
+// gather returns the bits in the accumulator followed
+// by the next 'k' bytes in the stream (without consuming
+// them), where k is the number of bytes needed to return
+// at least 'n' bits, along with the total number of bits
+func (b *BitReader) gather(n uint) (raw uint64, total uint, k int, err error) {
+	raw, total = b.acc, b.nbits
+	if n <= total {
+		return raw, total, 0, nil
+	}
+	k = int((n - total + 7) / 8)
+	p, err := b.r.Peek(k)
+	if err != nil {
+		if errors.Is(err, io.EOF) && (len(p) > 0 || total > 0) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, 0, err
+	}
+	for _, c := range p {
+		if b.order == MSBFirst {
+			raw = raw<<8 | uint64(c)
+		} else {
+			raw |= uint64(c) << total
+		}
+		total += 8
+	}
+	return raw, total, k, nil
+}
+
+// split returns the first 'n' bits of the
+// 'total' bits in 'raw', and the remaining bits
+func (b *BitReader) split(raw uint64, total, n uint) (v uint64, rest uint64) {
+	left := total - n
+	if b.order == MSBFirst {
+		return raw >> left, raw & (1<<left - 1)
+	}
+	return raw & (1<<n - 1), raw >> n
+}
+
+// PeekBits returns the next 'n' bits in the
+// stream without consuming them. 'n' may be
+// at most 57. The bits are returned in the low
+// bits of the result, with the first bit in the
+// stream as the most significant bit for MSBFirst,
+// and as the least significant bit for LSBFirst.
+func (b *BitReader) PeekBits(n uint) (uint64, error) {
+	if n > maxPeekBits {
+		return 0, ErrTooManyBits
+	}
+	raw, total, _, err := b.gather(n)
+	if err != nil {
+		return 0, err
+	}
+	v, _ := b.split(raw, total, n)
+	return v, nil
+}
+
+// ReadBits reads the next 'n' bits, where 'n'
+// is at most 64. The bits are returned as with
+// [BitReader.PeekBits]. If the stream ends before
+// 'n' bits are read, no bits are consumed (unless
+// 'n' is greater than 57, in which case some bits
+// may have been consumed).
+func (b *BitReader) ReadBits(n uint) (uint64, error) {
+	if n > 64 {
+		return 0, ErrTooManyBits
+	}
+	if n > maxPeekBits {
+		// too many bits to gather
+		// at once; read two pieces
+		const lo = 32
+		hi, err := b.ReadBits(n - lo)
+		if err != nil {
+			return 0, err
+		}
+		v, err := b.ReadBits(lo)
+		if err != nil {
+			return 0, err
+		}
+		if b.order == MSBFirst {
+			return hi<<lo | v, nil
+		}
+		return v<<(n-lo) | hi, nil
+	}
+	raw, total, k, err := b.gather(n)
+	if err != nil {
+		return 0, err
+	}
+	if k > 0 {
+		// these bytes are already buffered
+		b.r.Next(k)
+	}
+	v, rest := b.split(raw, total, n)
+	b.acc, b.nbits = rest, total-n
+	return v, nil
+}
+
+// ReadBit reads a single bit.
+func (b *BitReader) ReadBit() (bool, error) {
+	v, err := b.ReadBits(1)
+	return v != 0, err
+}
+
+// AlignToByte discards any bits remaining in
+// the current byte, so that the next read begins
+// at a byte boundary. Afterwards, the bit position
+// and the position of the Reader are the same.
+func (b *BitReader) AlignToByte() {
+	b.acc, b.nbits = 0, 0
+}
+
+// BitOffset returns the input stream offset
+// of the current bit position, in bits.
+func (b *BitReader) BitOffset() int64 {
+	return b.r.InputOffset()*8 - int64(b.nbits)
+}
+
+// InputOffset returns the input stream offset
+// of the byte containing the current bit position.
+func (b *BitReader) InputOffset() int64 {
+	return b.BitOffset() / 8
+}
+
+// BitWriter writes individual bits to a [Writer].
+type BitWriter struct {
+	w     *Writer
+	order BitOrder
+	acc   uint64 // pending bits, in the low nbits bits
+	nbits uint
+	count int64 // bits written
+}
+
+// NewBitWriter returns a BitWriter that
+// writes to 'w' with bits packed in 'order'.
+func NewBitWriter(w *Writer, order BitOrder) *BitWriter {
+	return &BitWriter{w: w, order: order}
+}
+
+// WriteBits writes the low 'n' bits of 'v', where
+// 'n' is at most 64. For MSBFirst, the most significant
+// of those bits is written first; for LSBFirst, the
+// least significant bit is written first.
+func (b *BitWriter) WriteBits(v uint64, n uint) error {
+	if n > 64 {
+		return ErrTooManyBits
+	}
+	if n > 32 {
+		// keep the accumulator from overflowing
+		lo := n - 32
+		if b.order == MSBFirst {
+			if err := b.WriteBits(v>>lo, 32); err != nil {
+				return err
+			}
+			return b.WriteBits(v, lo)
+		}
+		if err := b.WriteBits(v, 32); err != nil {
+			return err
+		}
+		return b.WriteBits(v>>32, lo)
+	}
+	if n < 64 {
+		v &= 1<<n - 1
+	}
+	if b.order == MSBFirst {
+		b.acc = b.acc<<n | v
+	} else {
+		b.acc |= v << b.nbits
+	}
+	b.nbits += n
+	b.count += int64(n)
+	for b.nbits >= 8 {
+		var c byte
+		if b.order == MSBFirst {
+			c = byte(b.acc >> (b.nbits - 8))
+		} else {
+			c = byte(b.acc)
+			b.acc >>= 8
+		}
+		b.nbits -= 8
+		if err := b.w.WriteByte(c); err != nil {
+			return err
+		}
+	}
+	if b.order == MSBFirst {
+		b.acc &= 1<<b.nbits - 1
+	}
+	return nil
+}
+
+// WriteBit writes a single bit.
+func (b *BitWriter) WriteBit(bit bool) error {
+	if bit {
+		return b.WriteBits(1, 1)
+	}
+	return b.WriteBits(0, 1)
+}
+
+// AlignToByte pads the current byte with zero
+// bits, if necessary, and writes it to the Writer,
+// so that the next write begins at a byte boundary.
+// It does not flush the Writer.
+func (b *BitWriter) AlignToByte() error {
+	if pad := (8 - b.nbits%8) % 8; pad > 0 {
+		return b.WriteBits(0, pad)
+	}
+	return nil
+}
+
+// BitOffset returns the number of
+// bits written by the BitWriter.
+func (b *BitWriter) BitOffset() int64 { return b.count }
@@ -0,0 +1,126 @@
+package fwd
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestBitsKnown(t *testing.T) {
+	for _, tc := range []struct {
+		order BitOrder
+		want  []byte
+	}{
+		// 1 0 0001111111 (0000)
+		{MSBFirst, []byte{0x87, 0xf0}},
+		// bit 0 = 1, bit 1 = 0, bits 2-11 = 0x7f
+		{LSBFirst, []byte{0xfd, 0x01}},
+	} {
+		var buf bytes.Buffer
+		wr := NewWriter(&buf)
+		bw := NewBitWriter(wr, tc.order)
+		bw.WriteBit(true)
+		bw.WriteBit(false)
+		bw.WriteBits(0x7f, 10)
+		if bw.BitOffset() != 12 {
+			t.Fatalf("expected bit offset 12; got %d", bw.BitOffset())
+		}
+		bw.AlignToByte()
+		wr.Flush()
+
+		if !bytes.Equal(buf.Bytes(), tc.want) {
+			t.Fatalf("order %d: expected %x; got %x", tc.order, tc.want, buf.Bytes())
+		}
+
+		br := NewBitReader(NewReaderBytes(buf.Bytes()), tc.order)
+		if bit, err := br.ReadBit(); err != nil || !bit {
+			t.Fatalf("ReadBit returned (%v, %v)", bit, err)
+		}
+		if bit, err := br.ReadBit(); err != nil || bit {
+			t.Fatalf("ReadBit returned (%v, %v)", bit, err)
+		}
+		if v, err := br.PeekBits(10); err != nil || v != 0x7f {
+			t.Fatalf("PeekBits returned (%#x, %v)", v, err)
+		}
+		if br.BitOffset() != 2 {
+			t.Fatalf("expected bit offset 2; got %d", br.BitOffset())
+		}
+		if v, err := br.ReadBits(10); err != nil || v != 0x7f {
+			t.Fatalf("ReadBits returned (%#x, %v)", v, err)
+		}
+		if br.BitOffset() != 12 || br.InputOffset() != 1 {
+			t.Fatalf("expected bit offset 12 at byte 1; got %d at %d", br.BitOffset(), br.InputOffset())
+		}
+		if _, err := br.ReadBits(5); err != io.ErrUnexpectedEOF {
+			t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+		}
+		br.AlignToByte()
+		if _, err := br.ReadBits(1); err != io.EOF {
+			t.Fatalf("expected %q; got %v", io.EOF, err)
+		}
+	}
+}
+
+func TestBitsRoundTrip(t *testing.T) {
+	type field struct {
+		v uint64
+		n uint
+	}
+	fields := make([]field, 2000)
+	for i := range fields {
+		n := uint(rand.Intn(65))
+		v := rand.Uint64()
+		if n < 64 {
+			v &= 1<<n - 1
+		}
+		fields[i] = field{v, n}
+	}
+
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		var buf bytes.Buffer
+		wr := NewWriterSize(&buf, 16)
+		bw := NewBitWriter(wr, order)
+		var bits int64
+		for _, f := range fields {
+			if err := bw.WriteBits(f.v, f.n); err != nil {
+				t.Fatal(err)
+			}
+			bits += int64(f.n)
+		}
+		if bw.BitOffset() != bits {
+			t.Fatalf("expected bit offset %d; got %d", bits, bw.BitOffset())
+		}
+		bw.AlignToByte()
+		wr.WriteByte(0xaa)
+		wr.Flush()
+
+		rd := NewReaderSize(partialReader{bytes.NewReader(buf.Bytes())}, 16)
+		br := NewBitReader(rd, order)
+		for i, f := range fields {
+			if f.n <= 57 {
+				v, err := br.PeekBits(f.n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v != f.v {
+					t.Fatalf("order %d field %d: peeked %#x; expected %#x", order, i, v, f.v)
+				}
+			}
+			v, err := br.ReadBits(f.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != f.v {
+				t.Fatalf("order %d field %d: read %#x; expected %#x", order, i, v, f.v)
+			}
+		}
+		if br.BitOffset() != bits {
+			t.Fatalf("expected bit offset %d; got %d", bits, br.BitOffset())
+		}
+		br.AlignToByte()
+		if b, err := rd.ReadByte(); err != nil || b != 0xaa {
+			t.Fatalf("ReadByte after AlignToByte returned (%#x, %v)", b, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package fwd
+
+import "encoding/binary"
+
+// Fixed is the set of fixed-size numeric
+// types supported by [ReadValues] and [WriteValues].
+type Fixed interface {
+	~int8 | ~uint8 | ~int16 | ~uint16 | ~int32 | ~uint32 | ~int64 | ~uint64 | ~float32 | ~float64
+}
+
+// ReadValues fills 'dst' with values read from 'r'
+// in the byte order 'order'. Whole runs of values are
+// copied out of the buffer at once (or read directly
+// from the underlying reader for large reads, like
+// [Reader.ReadFull]) and then converted in bulk. It returns
+// the number of complete values read, and, like ReadFull,
+// [io.ErrUnexpectedEOF] if the stream ends before 'dst' is full.
+func ReadValues[T Fixed](r *Reader, dst []T, order binary.ByteOrder) (int, error) {
+	return readValues(r, dst, order)
+}
+
+// WriteValues writes the values in 'src' to 'w'
+// in the byte order 'order'.
+func WriteValues[T Fixed](w *Writer, src []T, order binary.ByteOrder) error {
+	return writeValues(w, src, order)
+}
+
+// ReadUint16s is equivalent to ReadValues(r, dst, order).
+func (r *Reader) ReadUint16s(dst []uint16, order binary.ByteOrder) (int, error) {
+	return readValues(r, dst, order)
+}
+
+// ReadUint32s is equivalent to ReadValues(r, dst, order).
+func (r *Reader) ReadUint32s(dst []uint32, order binary.ByteOrder) (int, error) {
+	return readValues(r, dst, order)
+}
+
+// ReadUint64s is equivalent to ReadValues(r, dst, order).
+func (r *Reader) ReadUint64s(dst []uint64, order binary.ByteOrder) (int, error) {
+	return readValues(r, dst, order)
+}
+
+// ReadFloat32s is equivalent to ReadValues(r, dst, order).
+func (r *Reader) ReadFloat32s(dst []float32, order binary.ByteOrder) (int, error) {
+	return readValues(r, dst, order)
+}
+
+// ReadFloat64s is equivalent to ReadValues(r, dst, order).
+func (r *Reader) ReadFloat64s(dst []float64, order binary.ByteOrder) (int, error) {
+	return readValues(r, dst, order)
+}
+
+// WriteUint16s is equivalent to WriteValues(w, src, order).
+func (w *Writer) WriteUint16s(src []uint16, order binary.ByteOrder) error {
+	return writeValues(w, src, order)
+}
+
+// WriteUint32s is equivalent to WriteValues(w, src, order).
+func (w *Writer) WriteUint32s(src []uint32, order binary.ByteOrder) error {
+	return writeValues(w, src, order)
+}
+
+// WriteUint64s is equivalent to WriteValues(w, src, order).
+func (w *Writer) WriteUint64s(src []uint64, order binary.ByteOrder) error {
+	return writeValues(w, src, order)
+}
+
+// WriteFloat32s is equivalent to WriteValues(w, src, order).
+func (w *Writer) WriteFloat32s(src []float32, order binary.ByteOrder) error {
+	return writeValues(w, src, order)
+}
+
+// WriteFloat64s is equivalent to WriteValues(w, src, order).
+func (w *Writer) WriteFloat64s(src []float64, order binary.ByteOrder) error {
+	return writeValues(w, src, order)
+}
@@ -0,0 +1,26 @@
+//go:build appengine
+// +build appengine
+
+package fwd
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+func readValues[T Fixed](r *Reader, dst []T, order binary.ByteOrder) (int, error) {
+	var zero T
+	size := binary.Size(zero)
+	b := make([]byte, len(dst)*size)
+	n, err := r.ReadFull(b)
+	n /= size
+	if n > 0 {
+		// decode only the complete values
+		binary.Read(bytes.NewReader(b[:n*size]), order, dst[:n])
+	}
+	return n, err
+}
+
+func writeValues[T Fixed](w *Writer, src []T, order binary.ByteOrder) error {
+	return binary.Write(w, order, src)
+}
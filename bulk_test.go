@@ -0,0 +1,126 @@
+package fwd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+type celsius float32
+
+func TestBulkValues(t *testing.T) {
+	u16 := make([]uint16, 300)
+	u32 := make([]uint32, 300)
+	u64 := make([]uint64, 300)
+	f64 := make([]float64, 300)
+	temps := make([]celsius, 300)
+	for i := range u32 {
+		u16[i] = uint16(i * 0x0102)
+		u32[i] = uint32(i) * 0x01020304
+		u64[i] = uint64(i) * 0x0102030405060708
+		f64[i] = math.Pi * float64(i)
+		temps[i] = celsius(i) / 4
+	}
+
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		var buf bytes.Buffer
+		wr := NewWriterSize(&buf, 64)
+		wr.WriteByte(0) // misalign the buffer
+		if err := wr.WriteUint16s(u16, order); err != nil {
+			t.Fatal(err)
+		}
+		if err := wr.WriteUint32s(u32, order); err != nil {
+			t.Fatal(err)
+		}
+		if err := wr.WriteUint64s(u64, order); err != nil {
+			t.Fatal(err)
+		}
+		if err := wr.WriteFloat64s(f64, order); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteValues(wr, temps, order); err != nil {
+			t.Fatal(err)
+		}
+		wr.Flush()
+
+		// compare with encoding/binary
+		var want bytes.Buffer
+		want.WriteByte(0)
+		binary.Write(&want, order, u16)
+		binary.Write(&want, order, u32)
+		binary.Write(&want, order, u64)
+		binary.Write(&want, order, f64)
+		binary.Write(&want, order, temps)
+		if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+			t.Fatalf("%s: encoding doesn't match encoding/binary", order)
+		}
+
+		rd := NewReaderSize(partialReader{bytes.NewReader(buf.Bytes())}, 64)
+		rd.ReadByte()
+		out16 := make([]uint16, len(u16))
+		if n, err := rd.ReadUint16s(out16, order); err != nil || n != len(u16) {
+			t.Fatalf("ReadUint16s returned (%d, %v)", n, err)
+		}
+		out32 := make([]uint32, len(u32))
+		if n, err := rd.ReadUint32s(out32, order); err != nil || n != len(u32) {
+			t.Fatalf("ReadUint32s returned (%d, %v)", n, err)
+		}
+		out64 := make([]uint64, len(u64))
+		if n, err := rd.ReadUint64s(out64, order); err != nil || n != len(u64) {
+			t.Fatalf("ReadUint64s returned (%d, %v)", n, err)
+		}
+		outf := make([]float64, len(f64))
+		if n, err := rd.ReadFloat64s(outf, order); err != nil || n != len(f64) {
+			t.Fatalf("ReadFloat64s returned (%d, %v)", n, err)
+		}
+		outc := make([]celsius, len(temps))
+		if n, err := ReadValues(rd, outc, order); err != nil || n != len(temps) {
+			t.Fatalf("ReadValues returned (%d, %v)", n, err)
+		}
+		for i := range u32 {
+			if out16[i] != u16[i] || out32[i] != u32[i] || out64[i] != u64[i] || outf[i] != f64[i] || outc[i] != temps[i] {
+				t.Fatalf("%s: values at index %d not equal", order, i)
+			}
+		}
+	}
+
+	// the stream ends partway through
+	rd := NewReaderBytes(make([]byte, 4*10+2))
+	n, err := rd.ReadUint32s(make([]uint32, 20), binary.BigEndian)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+	}
+	if n != 10 {
+		t.Fatalf("expected to read 10 values; read %d", n)
+	}
+}
+
+func BenchmarkReadUint32s(b *testing.B) {
+	data := randomBts(1 << 16)
+	dst := make([]uint32, len(data)/4)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		rd := NewReaderBytes(data)
+		if _, err := rd.ReadUint32s(dst, binary.BigEndian); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadUint32sLoop(b *testing.B) {
+	data := randomBts(1 << 16)
+	dst := make([]uint32, len(data)/4)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		rd := NewReaderBytes(data)
+		for j := range dst {
+			v, err := rd.ReadUint32BE()
+			if err != nil {
+				b.Fatal(err)
+			}
+			dst[j] = v
+		}
+	}
+}
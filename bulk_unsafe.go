@@ -0,0 +1,171 @@
+//go:build !appengine
+// +build !appengine
+
+package fwd
+
+import (
+	"encoding/binary"
+	"unsafe"
+)
+
+// nativeOrder is the byte order of the host
+var nativeOrder binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// rawBytes returns the memory backing 's' as a []byte
+func rawBytes[T Fixed](s []T) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*int(unsafe.Sizeof(s[0])))
+}
+
+// words returns the memory backing 'b', which
+// must be suitably aligned, as a []W
+func words[W uint16 | uint32 | uint64](b []byte) []W {
+	var w W
+	return unsafe.Slice((*W)(unsafe.Pointer(&b[0])), len(b)/int(unsafe.Sizeof(w)))
+}
+
+func readValues[T Fixed](r *Reader, dst []T, order binary.ByteOrder) (int, error) {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	b := rawBytes(dst)
+	n, err := r.ReadFull(b)
+	n /= size
+	if n > 0 && size > 1 && order != nativeOrder {
+		b = b[:n*size]
+		// convert in place; each word is
+		// decoded before it is overwritten
+		switch size {
+		case 2:
+			w := words[uint16](b)
+			switch order {
+			case binary.BigEndian:
+				for i := range w {
+					w[i] = binary.BigEndian.Uint16(b[2*i:])
+				}
+			case binary.LittleEndian:
+				for i := range w {
+					w[i] = binary.LittleEndian.Uint16(b[2*i:])
+				}
+			default:
+				for i := range w {
+					w[i] = order.Uint16(b[2*i:])
+				}
+			}
+		case 4:
+			w := words[uint32](b)
+			switch order {
+			case binary.BigEndian:
+				for i := range w {
+					w[i] = binary.BigEndian.Uint32(b[4*i:])
+				}
+			case binary.LittleEndian:
+				for i := range w {
+					w[i] = binary.LittleEndian.Uint32(b[4*i:])
+				}
+			default:
+				for i := range w {
+					w[i] = order.Uint32(b[4*i:])
+				}
+			}
+		case 8:
+			w := words[uint64](b)
+			switch order {
+			case binary.BigEndian:
+				for i := range w {
+					w[i] = binary.BigEndian.Uint64(b[8*i:])
+				}
+			case binary.LittleEndian:
+				for i := range w {
+					w[i] = binary.LittleEndian.Uint64(b[8*i:])
+				}
+			default:
+				for i := range w {
+					w[i] = order.Uint64(b[8*i:])
+				}
+			}
+		}
+	}
+	return n, err
+}
+
+func writeValues[T Fixed](w *Writer, src []T, order binary.ByteOrder) error {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	b := rawBytes(src)
+	if size == 1 || order == nativeOrder {
+		_, err := w.Write(b)
+		return err
+	}
+	// encode as many values as fit
+	// into the free space in the buffer
+	for len(b) > 0 {
+		k := max(w.Available()/size, 1) * size
+		if k > len(b) {
+			k = len(b)
+		}
+		out, err := w.Next(k)
+		if err != nil {
+			return err
+		}
+		switch size {
+		case 2:
+			v := words[uint16](b[:k])
+			switch order {
+			case binary.BigEndian:
+				for i, x := range v {
+					binary.BigEndian.PutUint16(out[2*i:], x)
+				}
+			case binary.LittleEndian:
+				for i, x := range v {
+					binary.LittleEndian.PutUint16(out[2*i:], x)
+				}
+			default:
+				for i, x := range v {
+					order.PutUint16(out[2*i:], x)
+				}
+			}
+		case 4:
+			v := words[uint32](b[:k])
+			switch order {
+			case binary.BigEndian:
+				for i, x := range v {
+					binary.BigEndian.PutUint32(out[4*i:], x)
+				}
+			case binary.LittleEndian:
+				for i, x := range v {
+					binary.LittleEndian.PutUint32(out[4*i:], x)
+				}
+			default:
+				for i, x := range v {
+					order.PutUint32(out[4*i:], x)
+				}
+			}
+		case 8:
+			v := words[uint64](b[:k])
+			switch order {
+			case binary.BigEndian:
+				for i, x := range v {
+					binary.BigEndian.PutUint64(out[8*i:], x)
+				}
+			case binary.LittleEndian:
+				for i, x := range v {
+					binary.LittleEndian.PutUint64(out[8*i:], x)
+				}
+			default:
+				for i, x := range v {
+					order.PutUint64(out[8*i:], x)
+				}
+			}
+		}
+		b = b[k:]
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package fwd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// readDeadliner is implemented by streams
+// like net.Conn and *os.File that support
+// read deadlines
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// writeDeadliner is implemented by streams
+// like net.Conn and *os.File that support
+// write deadlines
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// aLongTimeAgo is a deadline that has
+// already passed, used to interrupt
+// blocked reads and writes
+var aLongTimeAgo = time.Unix(1, 0)
+
+// isTimeout returns whether or not
+// 'err' is a deadline being exceeded
+func isTimeout(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
+
+// withDeadline calls 'fn' with a goroutine
+// watching 'ctx' that calls 'set' with a deadline
+// in the past when it is done. It returns whether
+// or not 'ctx' was done by the time 'fn' returned,
+// in which case the deadline has been cleared, and
+// the error from 'fn'.
+func withDeadline(ctx context.Context, set func(time.Time) error, fn func() error) (bool, error) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			set(aLongTimeAgo)
+		case <-done:
+		}
+	}()
+	err := fn()
+	close(done)
+	<-exited
+	if ctx.Err() == nil {
+		return false, err
+	}
+	set(time.Time{})
+	return true, err
+}
+
+// readContext calls 'fn', interrupting it
+// if 'ctx' is done before it returns
+func (r *Reader) readContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, ok := r.r.(readDeadliner)
+	if !ok || ctx.Done() == nil {
+		return fn()
+	}
+	canceled, err := withDeadline(ctx, d.SetReadDeadline, fn)
+	if canceled && err != nil && isTimeout(err) {
+		// the timeout is ours; the
+		// buffered data is intact, so
+		// reset the reader for a retry
+		if isTimeout(r.state) {
+			r.state = nil
+		}
+		return ctx.Err()
+	}
+	return err
+}
+
+// PeekContext is like [Reader.Peek], except that it
+// returns ctx.Err() if 'ctx' is done before 'n' bytes
+// are available. If the underlying reader implements
+// SetReadDeadline (like a net.Conn), a blocked read is
+// interrupted by setting a deadline in the past when
+// 'ctx' is done; the read deadline is cleared afterwards.
+// Otherwise, 'ctx' is only checked before reading. Any
+// data read before 'ctx' was done remains buffered, so
+// the call can be retried.
+func (r *Reader) PeekContext(ctx context.Context, n int) ([]byte, error) {
+	if r.state == nil && r.buffered() >= n {
+		return r.data[r.n : r.n+n], nil
+	}
+	var b []byte
+	err := r.readContext(ctx, func() (err error) {
+		b, err = r.Peek(n)
+		return err
+	})
+	if err != nil && b == nil {
+		b = r.data[r.n:]
+	}
+	return b, err
+}
+
+// NextContext is like [Reader.Next], except that
+// it returns ctx.Err() if 'ctx' is done before 'n'
+// bytes are available. See [Reader.PeekContext] for
+// how reads are interrupted. The reader position is
+// not advanced if NextContext returns an error.
+func (r *Reader) NextContext(ctx context.Context, n int) ([]byte, error) {
+	if r.state == nil && r.buffered() >= n {
+		return r.Next(n)
+	}
+	var b []byte
+	err := r.readContext(ctx, func() (err error) {
+		b, err = r.Next(n)
+		return err
+	})
+	if err != nil && b == nil {
+		b = r.data[r.n:]
+	}
+	return b, err
+}
+
+// FlushContext is like [Writer.Flush], except that
+// it returns ctx.Err() if 'ctx' is done before the
+// buffered data has been written. If the underlying
+// writer implements SetWriteDeadline (like a net.Conn),
+// a blocked write is interrupted by setting a deadline
+// in the past when 'ctx' is done; the write deadline is
+// cleared afterwards. Otherwise, 'ctx' is only checked
+// before writing. Like other timeouts, the interruption
+// is not sticky: whatever was not written remains
+// buffered, so the call can be retried.
+func (w *Writer) FlushContext(ctx context.Context) error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, ok := w.w.(writeDeadliner)
+	if !ok || ctx.Done() == nil {
+		return w.Flush()
+	}
+	canceled, err := withDeadline(ctx, d.SetWriteDeadline, w.Flush)
+	if canceled && err != nil && isTimeout(err) {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package fwd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReaderContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	rd := NewReaderSize(server, 16)
+	go client.Write([]byte("abc"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	b, err := rd.PeekContext(ctx, 10)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded; got %v", err)
+	}
+	if string(b) != "abc" {
+		t.Fatalf("expected the buffered bytes; got %q", b)
+	}
+	if _, err := rd.NextContext(ctx, 10); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded; got %v", err)
+	}
+
+	// the buffered data survives for
+	// a retry with a fresh context
+	go client.Write([]byte("defghij"))
+	b, err = rd.NextContext(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "abcdefghij" {
+		t.Fatalf("got %q", b)
+	}
+
+	// buffered data is returned even
+	// if the context is already done
+	go client.Write([]byte("klm"))
+	if _, err := rd.Next(3); err != nil {
+		t.Fatal(err)
+	}
+	go client.Write([]byte("nop"))
+	rd.Peek(3)
+	b, err = rd.NextContext(ctx, 3)
+	if err != nil || string(b) != "nop" {
+		t.Fatalf("got %q, %v", b, err)
+	}
+}
+
+func TestWriterContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	data := randomBts(64)
+	wr := NewWriterSize(server, 64)
+	wr.Write(data)
+
+	// only read part of the buffered
+	// data before the flush is cancelled
+	first := make([]byte, 10)
+	go io.ReadFull(client, first)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := wr.FlushContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded; got %v", err)
+	}
+	if err := wr.FlushContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded; got %v", err)
+	}
+
+	rest := make([]byte, 64)
+	done := make(chan int)
+	go func() {
+		n, _ := io.ReadFull(client, rest[:wr.Buffered()])
+		done <- n
+	}()
+	if err := wr.FlushContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	n := <-done
+	if !bytes.Equal(append(first, rest[:n]...), data) {
+		t.Fatal("data not equal after retried flush")
+	}
+	if wr.OutputOffset() != 64 {
+		t.Fatalf("output offset %d", wr.OutputOffset())
+	}
+}
@@ -0,0 +1,89 @@
+package fwd
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Error is the type of error returned by a [Reader]
+// or [Writer] with detailed errors enabled (see
+// [Reader.SetDetailedErrors] and [Writer.SetDetailedErrors]).
+// It records the operation that failed and where in the
+// stream it failed. Use [errors.Is] and [errors.As] to
+// inspect the underlying error, e.g.
+//
+//	errors.Is(err, io.ErrUnexpectedEOF)
+type Error struct {
+	Op     string // operation that failed, e.g. "Next" or "Flush"
+	Size   int    // number of bytes requested, if applicable
+	Offset int64  // input or output offset at the time of failure
+	Err    error  // underlying error
+}
+
+func (e *Error) Error() string {
+	s := "fwd: " + e.Op
+	if e.Size > 0 {
+		s += "(" + strconv.Itoa(e.Size) + ")"
+	}
+	return s + " at offset " + strconv.FormatInt(e.Offset, 10) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// isTransient returns whether or not 'err'
+// is an error that is expected to go away if the
+// operation is retried, like a timeout or EAGAIN
+func isTransient(err error) bool {
+	if isTimeout(err) {
+		return true
+	}
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// SetDetailedErrors determines whether or not errors
+// returned by Peek, Next, Skip, and ReadFull are wrapped
+// in an *[Error] that records the input offset at which
+// the operation failed. Detailed errors are disabled
+// by default, in which case the underlying errors
+// (e.g. [io.ErrUnexpectedEOF]) are returned as-is.
+// Methods that implement standard interfaces, like
+// Read and ReadByte, never wrap their errors.
+func (r *Reader) SetDetailedErrors(on bool) {
+	r.detailed = on
+}
+
+// wrapErr wraps 'err' for the
+// operation 'op' if detailed errors
+// are enabled
+func (r *Reader) wrapErr(op string, size int, err error) error {
+	if !r.detailed || err == nil {
+		return err
+	}
+	return &Error{Op: op, Size: size, Offset: r.inputOffset, Err: err}
+}
+
+// SetDetailedErrors determines whether or not errors
+// returned by the Writer are wrapped in an *[Error]
+// that records the operation that failed and the output
+// offset up to which data had been successfully written.
+// Detailed errors are disabled by default, in which case
+// the errors returned by the underlying writer are
+// returned as-is.
+func (w *Writer) SetDetailedErrors(on bool) {
+	w.detailed = on
+}
+
+// wrapErr wraps 'err' for the
+// operation 'op' if detailed errors
+// are enabled
+func (w *Writer) wrapErr(op string, size int, err error) error {
+	if !w.detailed || err == nil {
+		return err
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{Op: op, Size: size, Offset: w.written, Err: err}
+}
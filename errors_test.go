@@ -0,0 +1,76 @@
+package fwd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDetailedErrors(t *testing.T) {
+	data := randomBts(100)
+
+	// errors are bare by default
+	rd := NewReaderSize(bytes.NewReader(data), 16)
+	rd.Skip(90)
+	if _, err := rd.Next(20); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+
+	rd = NewReaderSize(partialReader{bytes.NewReader(data)}, 16)
+	rd.SetDetailedErrors(true)
+	rd.Next(90)
+	_, err := rd.Next(20)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected an *Error; got %T", err)
+	}
+	if e.Op != "Next" || e.Size != 20 || e.Offset != 90 {
+		t.Fatalf("bad error %+v", e)
+	}
+	if s := err.Error(); s != "fwd: Next(20) at offset 90: unexpected EOF" {
+		t.Fatalf("bad error string %q", s)
+	}
+	if _, err := rd.Peek(20); !errors.Is(err, io.EOF) || !errors.As(err, &e) || e.Op != "Peek" {
+		t.Fatalf("bad Peek error %v", err)
+	}
+	if _, err := rd.Skip(20); !errors.Is(err, io.ErrUnexpectedEOF) || !errors.As(err, &e) || e.Offset != 100 {
+		t.Fatalf("bad Skip error %v", err)
+	}
+	// standard interfaces still
+	// return io.EOF as-is
+	if _, err := rd.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected io.EOF from Read; got %v", err)
+	}
+
+	wr := NewWriterSize(&failWriter{n: 10}, 16)
+	wr.SetDetailedErrors(true)
+	wr.Write(data[:16])
+	_, err = wr.Write(data[16:32])
+	if !errors.Is(err, errFail) || !errors.As(err, &e) {
+		t.Fatalf("expected a wrapped errFail; got %v", err)
+	}
+	if e.Op != "Flush" || e.Size != 16 || e.Offset != 10 {
+		t.Fatalf("bad error %+v", e)
+	}
+	if err := wr.Flush(); err != e {
+		t.Fatalf("expected the sticky error; got %v", err)
+	}
+}
+
+func BenchmarkNextDetailed(b *testing.B) {
+	data := randomBts(1 << 16)
+	rd := NewReaderBytes(data)
+	rd.SetDetailedErrors(true)
+	b.ReportAllocs()
+	b.SetBytes(8)
+	for i := 0; i < b.N; i++ {
+		if _, err := rd.Next(8); err != nil {
+			rd = NewReaderBytes(data)
+			rd.SetDetailedErrors(true)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package fwd
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Prefix describes the encoding of the
+// length prefix that precedes each frame
+// read by a [FrameReader] or written by
+// a [FrameWriter].
+type Prefix int
+
+const (
+	PrefixUint16BE Prefix = iota // big-endian uint16
+	PrefixUint16LE               // little-endian uint16
+	PrefixUint32BE               // big-endian uint32
+	PrefixUint32LE               // little-endian uint32
+	PrefixUint64BE               // big-endian uint64
+	PrefixUint64LE               // little-endian uint64
+	PrefixUvarint                // unsigned varint, as in [binary.PutUvarint]
+)
+
+// width returns the encoded size of a
+// fixed-width prefix, or 0 for PrefixUvarint
+func (p Prefix) width() int {
+	switch p {
+	case PrefixUint16BE, PrefixUint16LE:
+		return 2
+	case PrefixUint32BE, PrefixUint32LE:
+		return 4
+	case PrefixUint64BE, PrefixUint64LE:
+		return 8
+	}
+	return 0
+}
+
+// limit returns the largest frame size that
+// the prefix can encode and that 'max' allows
+func (p Prefix) limit(max int) uint64 {
+	l := uint64(math.MaxInt)
+	switch p {
+	case PrefixUint16BE, PrefixUint16LE:
+		l = math.MaxUint16
+	case PrefixUint32BE, PrefixUint32LE:
+		if math.MaxUint32 < l {
+			l = math.MaxUint32
+		}
+	}
+	if max > 0 && uint64(max) < l {
+		l = uint64(max)
+	}
+	return l
+}
+
+// put encodes 'size' into 'b', which
+// must be exactly p.width() bytes long
+func (p Prefix) put(b []byte, size uint64) {
+	switch p {
+	case PrefixUint16BE:
+		binary.BigEndian.PutUint16(b, uint16(size))
+	case PrefixUint16LE:
+		binary.LittleEndian.PutUint16(b, uint16(size))
+	case PrefixUint32BE:
+		binary.BigEndian.PutUint32(b, uint32(size))
+	case PrefixUint32LE:
+		binary.LittleEndian.PutUint32(b, uint32(size))
+	case PrefixUint64BE:
+		binary.BigEndian.PutUint64(b, size)
+	case PrefixUint64LE:
+		binary.LittleEndian.PutUint64(b, size)
+	}
+}
+
+// FrameSizeError is returned when a frame is
+// larger than the maximum size configured for
+// a [FrameReader] or [FrameWriter], or than
+// its length prefix can encode.
+type FrameSizeError struct {
+	Size   uint64 // size of the frame payload
+	Max    uint64 // maximum allowed payload size
+	Offset int64  // stream offset of the start of the frame
+}
+
+func (e *FrameSizeError) Error() string {
+	return "fwd: frame of " + strconv.FormatUint(e.Size, 10) +
+		" bytes at offset " + strconv.FormatInt(e.Offset, 10) +
+		" exceeds the limit of " + strconv.FormatUint(e.Max, 10) + " bytes"
+}
+
+// DefaultMaxFrameSize is the maximum payload size
+// accepted by a [FrameReader] that was created without
+// a maximum, when its Reader has no buffer limit either.
+const DefaultMaxFrameSize = 64 << 20
+
+// FrameReader reads length-prefixed frames
+// from a [Reader].
+type FrameReader struct {
+	r      *Reader
+	prefix Prefix
+	max    int
+}
+
+// NewFrameReader returns a FrameReader that reads
+// frames with length prefixes encoded as 'p' from 'r'.
+// Frames with payloads larger than 'max' bytes are
+// rejected; a 'max' of 0 or less means that frames
+// are limited by the size of the read buffer (see
+// [Reader.SetBufferLimit]) or, if it has no limit,
+// by [DefaultMaxFrameSize].
+func NewFrameReader(r *Reader, p Prefix, max int) *FrameReader {
+	return &FrameReader{r: r, prefix: p, max: max}
+}
+
+// ReadFrame reads the next frame and returns its
+// payload. Like the slice returned by [Reader.Next],
+// the payload is a view into the read buffer, and is
+// only valid until the next call to a method on the
+// underlying Reader. The buffer grows as necessary to
+// hold the payload.
+//
+// ReadFrame returns [io.EOF] if the stream ends cleanly
+// before the next frame, and [io.ErrUnexpectedEOF] if it
+// ends partway through one. If the frame is too large,
+// ReadFrame returns a *[FrameSizeError] before reading
+// (or making room for) the payload. In that case the
+// length prefix has been consumed, so the payload can be
+// passed over with [Reader.Skip].
+func (f *FrameReader) ReadFrame() ([]byte, error) {
+	r := f.r
+	off := r.InputOffset()
+	size, err := f.readPrefix()
+	if err != nil {
+		return nil, err
+	}
+	if l := f.limit(); size > l {
+		return nil, &FrameSizeError{Size: size, Max: l, Offset: off}
+	}
+	b, err := r.Next(int(size))
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// limit returns the largest payload
+// size that the FrameReader accepts
+func (f *FrameReader) limit() uint64 {
+	max := f.max
+	if max <= 0 {
+		max = f.r.maxBuf
+		if max <= 0 {
+			max = DefaultMaxFrameSize
+		}
+	}
+	return f.prefix.limit(max)
+}
+
+func (f *FrameReader) readPrefix() (uint64, error) {
+	r := f.r
+	if f.prefix == PrefixUvarint {
+		return r.ReadUvarint()
+	}
+	// a stream that ends between
+	// frames ends with io.EOF
+	if _, err := r.PeekByte(); err != nil {
+		return 0, err
+	}
+	b, err := r.Next(f.prefix.width())
+	if err != nil {
+		return 0, err
+	}
+	switch f.prefix {
+	case PrefixUint16BE:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case PrefixUint16LE:
+		return uint64(binary.LittleEndian.Uint16(b)), nil
+	case PrefixUint32BE:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case PrefixUint32LE:
+		return uint64(binary.LittleEndian.Uint32(b)), nil
+	case PrefixUint64BE:
+		return binary.BigEndian.Uint64(b), nil
+	default:
+		return binary.LittleEndian.Uint64(b), nil
+	}
+}
+
+// ErrInvalidFrame is returned by [FrameWriter.EndFrame]
+// when it is passed a [Frame] that is not in progress,
+// or a frame with something begun inside it still
+// in progress.
+var ErrInvalidFrame = errors.New("fwd: invalid frame")
+
+// FrameWriter writes length-prefixed frames
+// to a [Writer].
+type FrameWriter struct {
+	w      *Writer
+	prefix Prefix
+	max    int
+}
+
+// NewFrameWriter returns a FrameWriter that writes
+// frames with length prefixes encoded as 'p' to 'w'.
+// Frames with payloads larger than 'max' bytes are
+// rejected; a 'max' of 0 or less means that frames
+// are only limited by what the prefix can encode.
+func NewFrameWriter(w *Writer, p Prefix, max int) *FrameWriter {
+	return &FrameWriter{w: w, prefix: p, max: max}
+}
+
+// WriteFrame writes 'payload' as a single frame.
+// If the payload is too large, WriteFrame returns
+// a *[FrameSizeError] without writing anything.
+func (f *FrameWriter) WriteFrame(payload []byte) error {
+	w := f.w
+	size := uint64(len(payload))
+	if l := f.prefix.limit(f.max); size > l {
+		return &FrameSizeError{Size: size, Max: l, Offset: w.OutputOffset()}
+	}
+	if f.prefix == PrefixUvarint {
+		if err := w.WriteUvarint(size); err != nil {
+			return err
+		}
+	} else {
+		b, err := w.Next(f.prefix.width())
+		if err != nil {
+			return err
+		}
+		f.prefix.put(b, size)
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// Frame is a frame in progress, as
+// returned by [FrameWriter.BeginFrame].
+type Frame struct {
+	off int64  // output offset of the prefix
+	id  uint64 // id of the hold on the frame
+}
+
+// BeginFrame starts a new frame, the payload of which
+// is everything written to the underlying Writer until
+// the matching call to [FrameWriter.EndFrame]. Since the
+// length prefix is not known until then, the Writer holds
+// back the frame (growing its buffer as necessary) rather
+// than flushing it. Frames may be nested, as long as
+// they are ended in the reverse order that they
+// were begun.
+func (f *FrameWriter) BeginFrame() (Frame, error) {
+	w := f.w
+	if w.err != nil {
+		return Frame{}, w.err
+	}
+	if n := f.prefix.width(); n > 0 {
+		// reserve space for the prefix
+		b, err := w.Next(n)
+		if err != nil {
+			return Frame{}, err
+		}
+		for i := range b {
+			b[i] = 0
+		}
+		off := w.OutputOffset() - int64(n)
+		return Frame{off: off, id: w.hold(off)}, nil
+	}
+	// varint prefixes are inserted by EndFrame
+	off := w.OutputOffset()
+	return Frame{off: off, id: w.hold(off)}, nil
+}
+
+// EndFrame finishes the frame 'fr', filling in its length
+// prefix, and allows the Writer to flush it. If the frame
+// is too large, it is discarded and EndFrame returns a
+// *[FrameSizeError].
+//
+// Any frames, reservations and savepoints begun inside
+// the frame must be finished first, so nested frames
+// are ended in reverse order; until they are, EndFrame
+// returns [ErrInvalidFrame] and leaves the frame open.
+func (f *FrameWriter) EndFrame(fr Frame) error {
+	w := f.w
+	if !w.holding(fr.id) {
+		return ErrInvalidFrame
+	}
+	if w.err != nil {
+		w.release(fr.id)
+		return w.err
+	}
+	n := f.prefix.width()
+	i := int(fr.off - w.written)
+	size := uint64(len(w.buf) - i - n)
+	if l := f.prefix.limit(f.max); size > l {
+		w.discard(fr.id)
+		return &FrameSizeError{Size: size, Max: l, Offset: fr.off}
+	}
+	if w.holds[len(w.holds)-1].id != fr.id {
+		return ErrInvalidFrame
+	}
+	if n > 0 {
+		f.prefix.put(w.buf[i:i+n], size)
+		w.release(fr.id)
+		return nil
+	}
+	// insert the varint prefix
+	var tmp [binary.MaxVarintLen64]byte
+	k := binary.PutUvarint(tmp[:], size)
+	if err := w.grow(k); err != nil {
+		w.discard(fr.id)
+		return err
+	}
+	l := len(w.buf)
+	w.buf = w.buf[:l+k]
+	copy(w.buf[i+k:], w.buf[i:l])
+	copy(w.buf[i:], tmp[:k])
+	w.release(fr.id)
+	return nil
+}
@@ -0,0 +1,277 @@
+package fwd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+var prefixes = []Prefix{
+	PrefixUint16BE, PrefixUint16LE,
+	PrefixUint32BE, PrefixUint32LE,
+	PrefixUint64BE, PrefixUint64LE,
+	PrefixUvarint,
+}
+
+func TestFrames(t *testing.T) {
+	payloads := [][]byte{
+		nil,
+		[]byte("a"),
+		randomBts(100),
+		randomBts(300),
+		randomBts(5000),
+	}
+	for _, p := range prefixes {
+		var buf bytes.Buffer
+		wr := NewWriterSize(&buf, 64)
+		fw := NewFrameWriter(wr, p, 0)
+		for i, b := range payloads {
+			// alternate between whole frames
+			// and streamed ones
+			if i%2 == 0 {
+				if err := fw.WriteFrame(b); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			fr, err := fw.BeginFrame()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for len(b) > 0 {
+				n := 7
+				if n > len(b) {
+					n = len(b)
+				}
+				wr.Write(b[:n])
+				b = b[n:]
+			}
+			if err := fw.EndFrame(fr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := wr.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		rd := NewReaderSize(partialReader{bytes.NewReader(buf.Bytes())}, 16)
+		fr := NewFrameReader(rd, p, 0)
+		for _, b := range payloads {
+			got, err := fr.ReadFrame()
+			if err != nil {
+				t.Fatalf("prefix %d: %s", p, err)
+			}
+			if !bytes.Equal(got, b) {
+				t.Fatalf("prefix %d: payloads not equal", p)
+			}
+		}
+		if _, err := fr.ReadFrame(); err != io.EOF {
+			t.Fatalf("prefix %d: expected io.EOF; got %v", p, err)
+		}
+	}
+}
+
+func TestFrameNested(t *testing.T) {
+	for _, p := range prefixes {
+		var buf bytes.Buffer
+		wr := NewWriterSize(&buf, 16)
+		fw := NewFrameWriter(wr, p, 0)
+		outer, _ := fw.BeginFrame()
+		wr.WriteString("head")
+		inner, _ := fw.BeginFrame()
+		wr.WriteString("the inner frame payload")
+		if err := fw.EndFrame(outer); err != ErrInvalidFrame {
+			t.Fatalf("expected ErrInvalidFrame; got %v", err)
+		}
+		if err := fw.EndFrame(inner); err != nil {
+			t.Fatal(err)
+		}
+		wr.WriteString("tail")
+		if err := fw.EndFrame(outer); err != nil {
+			t.Fatal(err)
+		}
+		if err := fw.EndFrame(outer); err != ErrInvalidFrame {
+			t.Fatalf("expected ErrInvalidFrame; got %v", err)
+		}
+		wr.Flush()
+
+		fr := NewFrameReader(NewReader(&buf), p, 0)
+		b, err := fr.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fr = NewFrameReader(NewReaderBytes(b[4:len(b)-4]), p, 0)
+		b, err = fr.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "the inner frame payload" {
+			t.Fatalf("prefix %d: got %q", p, b)
+		}
+	}
+}
+
+func TestFrameHeld(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 16)
+	fw := NewFrameWriter(wr, PrefixUint32BE, 0)
+	wr.WriteString("before")
+	fr, _ := fw.BeginFrame()
+	wr.Write(randomBts(100))
+	wr.ReadFrom(bytes.NewReader(randomBts(100)))
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "before" {
+		t.Fatalf("flushed %q", buf.String())
+	}
+	if err := wr.Close(); err == nil {
+		t.Fatal("expected an error closing with a frame in progress")
+	}
+	fw.EndFrame(fr)
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 6+4+200 {
+		t.Fatalf("flushed %d bytes", buf.Len())
+	}
+	if wr.BufferSize() <= 16 {
+		t.Fatal("buffer didn't grow")
+	}
+}
+
+func TestFrameReserve(t *testing.T) {
+	for _, p := range prefixes {
+		var buf bytes.Buffer
+		wr := NewWriter(&buf)
+		fw := NewFrameWriter(wr, p, 0)
+		fr, _ := fw.BeginFrame()
+		wr.WriteString("ab")
+		r, _ := wr.Reserve(2)
+		wr.WriteString("cd")
+		// the reservation must be filled first
+		if err := fw.EndFrame(fr); err != ErrInvalidFrame {
+			t.Fatalf("expected ErrInvalidFrame; got %v", err)
+		}
+		if err := r.Fill([]byte("XY")); err != nil {
+			t.Fatal(err)
+		}
+		if err := fw.EndFrame(fr); err != nil {
+			t.Fatalf("prefix %d: %s", p, err)
+		}
+		if err := wr.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := NewFrameReader(NewReaderBytes(buf.Bytes()), p, 0).ReadFrame()
+		if err != nil || string(b) != "abXYcd" {
+			t.Fatalf("prefix %d: got %q, %v", p, b, err)
+		}
+	}
+}
+
+func TestFrameSize(t *testing.T) {
+	for _, p := range prefixes {
+		var buf bytes.Buffer
+		wr := NewWriter(&buf)
+		fw := NewFrameWriter(wr, p, 10)
+		var fse *FrameSizeError
+		if err := fw.WriteFrame(make([]byte, 11)); !errors.As(err, &fse) {
+			t.Fatalf("expected a FrameSizeError; got %v", err)
+		}
+		if fse.Size != 11 || fse.Max != 10 {
+			t.Fatalf("bad error %+v", fse)
+		}
+		wr.WriteString("x")
+		fr, _ := fw.BeginFrame()
+		wr.Write(make([]byte, 11))
+		if err := fw.EndFrame(fr); !errors.As(err, &fse) || fse.Offset != 1 {
+			t.Fatalf("expected a FrameSizeError at offset 1; got %v", err)
+		}
+		wr.Flush()
+		if buf.String() != "x" {
+			t.Fatalf("oversized frame was written: %q", buf.String())
+		}
+
+		// holds placed inside an oversized
+		// frame are discarded along with it
+		wr.Reset(&buf)
+		buf.Reset()
+		wr.WriteString("ok|")
+		fr, _ = fw.BeginFrame()
+		wr.WriteString("SECRET")
+		wr.Reserve(2)
+		wr.WriteString("LEAKED")
+		if err := fw.EndFrame(fr); !errors.As(err, &fse) {
+			t.Fatalf("expected a FrameSizeError; got %v", err)
+		}
+		wr.WriteString("next")
+		if err := wr.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "ok|next" {
+			t.Fatalf("got %q", buf.String())
+		}
+
+		// the reader must not try to buffer
+		// the payload of an oversized frame
+		wr.Reset(&buf)
+		buf.Reset()
+		NewFrameWriter(wr, p, 0).WriteFrame(make([]byte, 100))
+		NewFrameWriter(wr, p, 0).WriteFrame([]byte("ok"))
+		wr.Flush()
+		rd := NewReaderSize(&buf, 16)
+		rd.SetBufferLimit(16)
+		frd := NewFrameReader(rd, p, 50)
+		if _, err := frd.ReadFrame(); !errors.As(err, &fse) || fse.Size != 100 {
+			t.Fatalf("expected a FrameSizeError; got %v", err)
+		}
+		if rd.BufferSize() != 16 {
+			t.Fatal("buffer grew for an oversized frame")
+		}
+		rd.Skip(100)
+		b, err := frd.ReadFrame()
+		if err != nil || string(b) != "ok" {
+			t.Fatalf("got %q, %v", b, err)
+		}
+	}
+
+	// the prefix limits the frame size too
+	fw := NewFrameWriter(NewWriter(io.Discard), PrefixUint16LE, 0)
+	if err := fw.WriteFrame(make([]byte, 1<<16)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestFrameTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriter(&buf)
+	NewFrameWriter(wr, PrefixUint32LE, 0).WriteFrame([]byte("hello"))
+	wr.Flush()
+	for i := 1; i < buf.Len(); i++ {
+		fr := NewFrameReader(NewReaderBytes(buf.Bytes()[:i]), PrefixUint32LE, 0)
+		if _, err := fr.ReadFrame(); err != io.ErrUnexpectedEOF {
+			t.Fatalf("%d bytes: expected io.ErrUnexpectedEOF; got %v", i, err)
+		}
+	}
+}
+
+func TestFrameHostilePrefix(t *testing.T) {
+	var hdr [8]byte
+	binary.BigEndian.PutUint64(hdr[:], 1<<60)
+	var fse *FrameSizeError
+
+	fr := NewFrameReader(NewReaderBytes(hdr[:]), PrefixUint64BE, 0)
+	if _, err := fr.ReadFrame(); !errors.As(err, &fse) || fse.Max != DefaultMaxFrameSize {
+		t.Fatalf("expected a FrameSizeError; got %v", err)
+	}
+
+	// the buffer limit applies when there's no maximum
+	rd := NewReader(bytes.NewReader(hdr[:]))
+	rd.SetBufferLimit(4096)
+	fr = NewFrameReader(rd, PrefixUint64BE, 0)
+	if _, err := fr.ReadFrame(); !errors.As(err, &fse) || fse.Max != 4096 {
+		t.Fatalf("expected a FrameSizeError; got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build linux && !appengine && !tinygo
+// +build linux,!appengine,!tinygo
+
+package fwd
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the contents of 'f' into memory
+// read-only and returns the mapping along with
+// a function that releases it
+func mmap(f *os.File) ([]byte, func() error, bool) {
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return nil, nil, false
+	}
+	size := fi.Size()
+	if size <= 0 || int64(int(size)) != size {
+		return nil, nil, false
+	}
+	b, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, false
+	}
+	return b, func() error { return syscall.Munmap(b) }, true
+}
@@ -0,0 +1,11 @@
+//go:build !linux || appengine || tinygo
+// +build !linux appengine tinygo
+
+package fwd
+
+import "os"
+
+// mmap is only supported on linux
+func mmap(f *os.File) ([]byte, func() error, bool) {
+	return nil, nil, false
+}
@@ -0,0 +1,174 @@
+package fwd
+
+import (
+	"io"
+	"os"
+)
+
+// NewReaderPrefetch returns a new *Reader that reads
+// from 'r' with a buffer of 'n' bytes, like
+// [NewReaderSize], except that reads from 'r' happen
+// in a background goroutine, into a second buffer of
+// 'n' bytes, so that the next buffer of data is read
+// while the current one is being consumed. This is
+// useful for sources with high latency, like network
+// filesystems or pipes from slow producers.
+//
+// Errors from 'r' are returned after all of the data
+// that preceded them. The slices returned by methods
+// like [Reader.Peek] and [Reader.Next] remain valid
+// until the next reader method call, just like for
+// any other Reader.
+//
+// Prefetching Readers never seek. [Reader.Close] stops
+// the background goroutine, and [Reader.Reset] starts a
+// new one for the new reader. Neither waits for a read
+// from 'r' that is in progress: the goroutine discards
+// whatever that read returns and exits once it returns.
+// Since Close doesn't close 'r', callers should close it
+// themselves if a read from it may block indefinitely.
+func NewReaderPrefetch(r io.Reader, n int) *Reader {
+	n = max(n, minReaderSize)
+	pf := newPrefetcher(r, n, defaultRetries)
+	rd := NewReaderSize(pf, n)
+	rd.pf = pf
+	rd.closer = pf.stop
+	return rd
+}
+
+// chunk is the result of one read
+// by the prefetching goroutine
+type chunk struct {
+	buf []byte // buffer read into
+	n   int    // bytes read
+	err error  // error from the read
+}
+
+// prefetcher is an io.Reader that
+// reads from r in the background
+type prefetcher struct {
+	r       io.Reader
+	retries int
+
+	// exactly one buffer belongs to the
+	// prefetcher at any time: either the
+	// goroutine has it, or it's in one
+	// of the channels, or it's p.buf
+	full    chan chunk
+	free    chan []byte
+	done    chan struct{}
+	stopped bool
+
+	buf  []byte // buffer of the current chunk, if any
+	cur  []byte // unread part of the current chunk
+	err  error  // error to return after cur
+	term error  // returned once there are no more chunks
+}
+
+func newPrefetcher(r io.Reader, size, retries int) *prefetcher {
+	p := &prefetcher{
+		r:       r,
+		retries: retries,
+		full:    make(chan chunk, 1),
+		free:    make(chan []byte, 1),
+		done:    make(chan struct{}),
+	}
+	p.free <- make([]byte, size)
+	go p.run()
+	return p
+}
+
+func (p *prefetcher) run() {
+	for {
+		var buf []byte
+		select {
+		case buf = <-p.free:
+		case <-p.done:
+			return
+		}
+		var n int
+		var err error
+		for i := 0; ; i++ {
+			n, err = p.r.Read(buf[:cap(buf)])
+			if n > 0 || err != nil {
+				break
+			}
+			if i >= p.retries {
+				err = io.ErrNoProgress
+				break
+			}
+		}
+		select {
+		case p.full <- chunk{buf: buf, n: n, err: err}:
+		case <-p.done:
+			return
+		}
+		if err != nil && !isTransient(err) {
+			return
+		}
+	}
+}
+
+// stop tells the goroutine to exit, without waiting
+// for a read in progress; subsequent reads return
+// os.ErrClosed
+func (p *prefetcher) stop() error {
+	if !p.stopped {
+		p.stopped = true
+		close(p.done)
+	}
+	p.buf, p.cur, p.err = nil, nil, nil
+	p.term = os.ErrClosed
+	return nil
+}
+
+// recv waits for the next chunk; it returns
+// false if there will never be another one
+func (p *prefetcher) recv() bool {
+	if p.term != nil {
+		return false
+	}
+	c := <-p.full
+	p.buf, p.cur, p.err = c.buf, c.buf[:c.n], c.err
+	return true
+}
+
+// consumed hands the buffer of the current chunk
+// back to the goroutine once it has been read, and
+// returns the error that came with the chunk
+func (p *prefetcher) consumed() error {
+	p.free <- p.buf
+	p.buf, p.cur = nil, nil
+	err := p.err
+	p.err = nil
+	if err != nil && !isTransient(err) {
+		p.term = err
+	}
+	return err
+}
+
+// Read implements io.Reader
+func (p *prefetcher) Read(b []byte) (int, error) {
+	if p.buf == nil && !p.recv() {
+		return 0, p.term
+	}
+	n := copy(b, p.cur)
+	p.cur = p.cur[n:]
+	if len(p.cur) == 0 {
+		return n, p.consumed()
+	}
+	return n, nil
+}
+
+// swap returns the next chunk of data as a
+// buffer to use in place of 'old', which may
+// be handed to the goroutine to read into
+func (p *prefetcher) swap(old []byte) ([]byte, error) {
+	if p.buf != nil || !p.recv() || cap(p.buf) < cap(old) {
+		n, err := p.Read(old[:cap(old)])
+		return old[:n], err
+	}
+	b := p.cur
+	p.buf = old
+	return b, p.consumed()
+}
@@ -0,0 +1,140 @@
+package fwd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPrefetch(t *testing.T) {
+	bts := randomBts(1 << 16)
+	rd := NewReaderPrefetch(partialReader{bytes.NewReader(bts)}, 64)
+	defer rd.Close()
+
+	var out []byte
+	for i := 0; len(out) < len(bts); i++ {
+		switch i % 4 {
+		case 0:
+			b, err := rd.Next(100)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				t.Fatal(err)
+			}
+			if err == nil {
+				out = append(out, b...)
+			} else {
+				b, _ = rd.Next(rd.Buffered())
+				out = append(out, b...)
+			}
+		case 1:
+			b, err := rd.ReadByte()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, b)
+		case 2:
+			p, _ := rd.Peek(10)
+			b, _ := rd.Next(len(p))
+			if !bytes.Equal(p, b) {
+				t.Fatal("Peek and Next disagree")
+			}
+			out = append(out, b...)
+		case 3:
+			buf := make([]byte, 500)
+			n, err := rd.Read(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, buf[:n]...)
+		}
+	}
+	if !bytes.Equal(out, bts) {
+		t.Fatal("bytes not equal")
+	}
+	if _, err := rd.ReadByte(); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	if rd.InputOffset() != int64(len(bts)) {
+		t.Fatalf("expected offset %d; got %d", len(bts), rd.InputOffset())
+	}
+}
+
+// errAfterReader returns data from r,
+// then err instead of io.EOF
+type errAfterReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errAfterReader) Read(b []byte) (int, error) {
+	n, err := e.r.Read(b)
+	if err == io.EOF {
+		err = e.err
+	}
+	return n, err
+}
+
+func TestPrefetchErrors(t *testing.T) {
+	bts := randomBts(1000)
+	errBoom := errors.New("boom")
+	rd := NewReaderPrefetch(&errAfterReader{r: bytes.NewReader(bts), err: errBoom}, 64)
+	out, err := ioutil.ReadAll(rd)
+	if err != errBoom {
+		t.Fatalf("expected errBoom; got %v", err)
+	}
+	if !bytes.Equal(out, bts) {
+		t.Fatal("data before the error was lost")
+	}
+	if _, err := rd.ReadByte(); err != errBoom {
+		t.Fatalf("expected errBoom again; got %v", err)
+	}
+	if err := rd.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rd.ReadByte(); err != os.ErrClosed {
+		t.Fatalf("expected os.ErrClosed after Close; got %v", err)
+	}
+
+	// Reset starts prefetching again
+	rd.Reset(bytes.NewReader(bts))
+	out, err = ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts) {
+		t.Fatal("bytes not equal after Reset")
+	}
+	rd.Close()
+}
+
+func TestPrefetchClose(t *testing.T) {
+	pr, pw := io.Pipe()
+	rd := NewReaderPrefetch(pr, 64)
+	go pw.Write([]byte("hello"))
+	b, err := rd.Next(5)
+	if err != nil || string(b) != "hello" {
+		t.Fatalf("got %q, %v", b, err)
+	}
+	// the goroutine is blocked reading the
+	// pipe, which mustn't hold up Close
+	done := make(chan error, 1)
+	go func() { done <- rd.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked on a read in progress")
+	}
+	if _, err := rd.ReadByte(); err != os.ErrClosed {
+		t.Fatalf("expected os.ErrClosed after Close; got %v", err)
+	}
+	// the goroutine drops the data from
+	// its last read and exits
+	pw.Write([]byte("dropped"))
+	pw.Close()
+}
@@ -34,8 +34,13 @@
 package fwd
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"io"
+	"math"
 	"os"
+	"unicode/utf8"
 )
 
 const (
@@ -44,6 +49,10 @@ const (
 
 	// minimum read buffer; straight from bufio
 	minReaderSize = 16
+
+	// empty reads to retry before
+	// io.ErrNoProgress; also from bufio
+	defaultRetries = 100
 )
 
 // NewReader returns a new *Reader that reads from 'r'
@@ -68,12 +77,53 @@ func NewReaderBuf(r io.Reader, buf []byte) *Reader {
 	}
 	buf = buf[:0]
 	rd := &Reader{
-		r:    r,
-		data: buf,
+		r:       r,
+		data:    buf,
+		bufSize: cap(buf),
+		retries: defaultRetries,
+	}
+	rd.setSeeker(r)
+	return rd
+}
+
+// NewReaderBytes returns a new *Reader that
+// reads from 'b' directly. No data is copied;
+// the slices returned by methods like [Reader.Peek],
+// [Reader.Next] and [Reader.ReadSlice] point into 'b',
+// and the buffer size limit does not apply.
+// The caller must not modify 'b' while the
+// reader is in use.
+func NewReaderBytes(b []byte) *Reader {
+	return &Reader{
+		data:    b[:len(b):len(b)],
+		static:  true,
+		retries: defaultRetries,
+	}
+}
+
+// NewReaderFile returns a new *Reader that reads
+// from 'f' starting at its current offset. Where
+// supported, the file is mapped into memory read-only
+// and read without copying, as with [NewReaderBytes];
+// reading from the returned Reader does not advance
+// the offset of 'f'. If the file cannot be mapped,
+// NewReaderFile falls back to [NewReader].
+// The caller should call [Reader.Close] to release
+// the mapping once the reader is no longer in use.
+func NewReaderFile(f *os.File) *Reader {
+	b, unmap, ok := mmap(f)
+	if !ok {
+		return NewReader(f)
 	}
-	if s, ok := r.(io.Seeker); ok {
-		rd.rs = s
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil || pos > int64(len(b)) {
+		unmap()
+		return NewReader(f)
 	}
+	rd := NewReaderBytes(b)
+	rd.n = int(pos)
+	rd.inputOffset = pos
+	rd.closer = unmap
 	return rd
 }
 
@@ -86,54 +136,347 @@ type Reader struct {
 	n           int    // read offset
 	inputOffset int64  // offset in the input stream
 	state       error  // last read error
+	lastRune    int    // size of the last rune read by ReadRune
+	runeOffset  int64  // input offset after the last ReadRune
+
+	// while there are outstanding marks (by
+	// id), data from markOffset onwards is
+	// retained in the buffer
+	marks      []uint64
+	markID     uint64 // id of the last mark
+	markOffset int64
+
+	// when limited is set, the input
+	// stream ends at offset limit; any bytes
+	// that were buffered past the limit
+	// when it was set are kept in excess,
+	// or, for static readers, where they
+	// remain in place, counted by hidden
+	limited bool
+	limit   int64
+	excess  []byte
+	hidden  int
+
+	// static is set when data is the whole
+	// input stream and must not be modified;
+	// closer releases it, if necessary
+	static bool
+	closer func() error
+
+	// buffer growth policy
+	bufSize int  // original buffer size
+	maxBuf  int  // maximum buffer size; 0 means no limit
+	shrink  bool // return to bufSize once possible
+
+	detailed bool // wrap errors in *Error
+	retries  int  // empty reads to retry before io.ErrNoProgress
 
 	// if the reader past to NewReader was
 	// also an io.Seeker, this is non-nil
-	rs io.Seeker
+	rs     io.Seeker
+	rsSize int64 // last known size of rs; see seekerSize
+
+	// bytes skipped past inputOffset in the
+	// underlying reader that haven't been
+	// seeked past or discarded yet
+	pending int64
+
+	// if the reader was created with
+	// NewReaderPrefetch, this is non-nil
+	pf *prefetcher
+}
+
+// ErrBufferLimit is returned when satisfying
+// a read or write would require growing the buffer
+// past the limit set with [Reader.SetBufferLimit]
+// or [Writer.SetBufferLimit].
+var ErrBufferLimit = errors.New("fwd: buffer size limit exceeded")
+
+// ErrLimitExceeded is returned when a read
+// would go past the limit set with [Reader.SetLimit].
+var ErrLimitExceeded = errors.New("fwd: read limit exceeded")
+
+// SetLimit limits the reader to the next 'n'
+// bytes of the input stream. Once the limit
+// is reached, the reader behaves as if the stream
+// had ended, except that [ErrLimitExceeded] is
+// returned instead of [io.EOF]. To read up to
+// an absolute offset 'x' in the input stream,
+// use SetLimit(x - r.InputOffset()).
+// SetLimit replaces any previous limit.
+func (r *Reader) SetLimit(n int64) {
+	r.ClearLimit()
+	r.limited = true
+	r.limit = r.inputOffset + max64(n, 0)
+	// hide any buffered data past the limit
+	if over := r.inputOffset + int64(r.buffered()) - r.limit; over > 0 {
+		end := len(r.data) - int(over)
+		if r.static {
+			r.hidden = int(over)
+		} else {
+			r.excess = append(r.excess[:0], r.data[end:]...)
+		}
+		r.data = r.data[:end]
+	}
+}
+
+// ClearLimit removes the limit set with [Reader.SetLimit].
+func (r *Reader) ClearLimit() {
+	if !r.limited {
+		return
+	}
+	r.limited = false
+	if r.state == ErrLimitExceeded {
+		r.state = nil
+	}
+	if r.hidden > 0 {
+		// the excess is still in place
+		r.data = r.data[:len(r.data)+r.hidden]
+		r.hidden = 0
+	}
+	if len(r.excess) > 0 {
+		r.data = append(r.data, r.excess...)
+		r.excess = r.excess[:0]
+	}
+}
+
+// remaining returns the number of bytes
+// between the end of the buffered data
+// and the limit
+func (r *Reader) remaining() int64 {
+	return r.limit - r.inputOffset - int64(r.buffered())
+}
+
+// SetBufferLimit sets the maximum size that the
+// read buffer may grow to in order to satisfy calls
+// like [Reader.Peek] and [Reader.Next]. Reads that would
+// require a larger buffer fail with [ErrBufferLimit]
+// before any memory is allocated. A limit of 0 or less
+// means that the buffer may grow without bound, which
+// is the default.
+func (r *Reader) SetBufferLimit(n int) {
+	r.maxBuf = max(n, 0)
+}
+
+// SetBufferShrink determines whether or not the
+// read buffer returns to its original size once
+// the data that required it to grow has been consumed.
+// By default, the buffer never shrinks.
+func (r *Reader) SetBufferShrink(shrink bool) {
+	r.shrink = shrink
 }
 
 // Reset resets the underlying reader
 // and the read buffer.
 func (r *Reader) Reset(rd io.Reader) {
+	if r.static {
+		// don't read into memory
+		// that isn't ours
+		r.Close()
+		r.static = false
+		r.data = make([]byte, 0, DefaultReaderSize)
+		r.bufSize = cap(r.data)
+	}
+	if r.pf != nil {
+		// start prefetching from 'rd' instead
+		r.pf.stop()
+		if r.data == nil {
+			r.data = make([]byte, 0, r.bufSize)
+		}
+		r.pf = newPrefetcher(rd, r.bufSize, r.retries)
+		r.closer = r.pf.stop
+		rd = r.pf
+	}
 	r.r = rd
 	r.data = r.data[0:0]
 	r.n = 0
 	r.inputOffset = 0
 	r.state = nil
+	r.lastRune = 0
+	r.marks = r.marks[:0]
+	r.limited = false
+	r.excess = r.excess[:0]
+	r.hidden = 0
+	r.pending = 0
+	r.setSeeker(rd)
+}
+
+// Close releases any resources held by the
+// reader, such as the memory mapping created
+// by [NewReaderFile]. It does not close the
+// underlying reader. Slices previously returned
+// by the reader must not be used after Close.
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	c := r.closer
+	r.closer = nil
+	r.data = nil
+	r.n = 0
+	return c()
+}
+
+// setSeeker sets r.rs if 'rd' is an io.Seeker
+// that can actually seek (an *os.File for a pipe
+// can't, for example), and initializes the input
+// offset from the current position of the seeker
+func (r *Reader) setSeeker(rd io.Reader) {
+	r.rs = nil
 	if s, ok := rd.(io.Seeker); ok {
-		r.rs = s
-	} else {
-		r.rs = nil
+		if pos, err := s.Seek(0, io.SeekCurrent); err == nil {
+			r.rs = s
+			r.rsSize = 0
+			r.inputOffset = pos
+		}
 	}
 }
 
+// seekerSize updates and returns r.rsSize, which
+// is the size of the underlying reader if it is
+// a regular file, and math.MaxInt64 otherwise
+func (r *Reader) seekerSize() int64 {
+	f, ok := r.rs.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		r.rsSize = math.MaxInt64
+		return r.rsSize
+	}
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		r.rsSize = math.MaxInt64
+		return r.rsSize
+	}
+	r.rsSize = fi.Size()
+	return r.rsSize
+}
+
 // more() does one read on the underlying reader
 func (r *Reader) more() {
+	free := -1 // maximum bytes to read; -1 is unbounded
+	if r.limited {
+		rem := r.remaining()
+		if rem <= 0 {
+			r.state = ErrLimitExceeded
+			return
+		}
+		if rem < int64(cap(r.data)) {
+			free = int(rem)
+		}
+	}
+	// the whole input is already in data
+	if r.static {
+		r.state = io.EOF
+		return
+	}
 	// move data backwards so that
-	// the read offset is 0; this way
-	// we can supply the maximum number of
-	// bytes to the reader
-	if r.n != 0 {
-		if r.n < len(r.data) {
-			r.data = r.data[:copy(r.data[0:], r.data[r.n:])]
+	// the read offset is 0 (or the oldest
+	// mark is at 0); this way we can supply
+	// the maximum number of bytes to the reader
+	if k := r.keep(); k != 0 {
+		if k < len(r.data) {
+			r.data = r.data[:copy(r.data[0:], r.data[k:])]
 		} else {
 			r.data = r.data[:0]
 		}
-		r.n = 0
+		r.n -= k
 	}
-	var a int
-	a, r.state = r.r.Read(r.data[len(r.data):cap(r.data)])
-	if a == 0 && r.state == nil {
-		r.state = io.ErrNoProgress
+	// the prefetcher can hand over a whole
+	// buffer of data if there's nothing to keep
+	if r.pf != nil && len(r.data) == 0 && free < 0 {
+		r.data, r.state = r.pf.swap(r.data)
+		if len(r.data) > 0 && r.state == io.EOF {
+			r.state = nil
+		}
 		return
-	} else if a > 0 && r.state == io.EOF {
+	}
+	// the buffer may be full of retained
+	// data (e.g. an outstanding mark, or
+	// a long ReadSlice), in which case we
+	// have to make more room
+	if len(r.data) == cap(r.data) {
+		if r.state = r.grow(r.buffered() + 1); r.state != nil {
+			return
+		}
+	}
+	end := cap(r.data)
+	if free >= 0 && len(r.data)+free < end {
+		end = len(r.data) + free
+	}
+	var a int
+	a, r.state = r.read(r.data[len(r.data):end])
+	// keep whatever was read, even
+	// if it came with an error
+	r.data = r.data[:len(r.data)+a]
+	if a > 0 && r.state == io.EOF {
 		// discard the io.EOF if we read more than 0 bytes.
 		// the next call to Read should return io.EOF again.
 		r.state = nil
-	} else if r.state != nil {
+	}
+}
+
+// read reads from the underlying reader into 'b',
+// retrying reads that return neither data nor an error
+// up to r.retries times before giving up with
+// io.ErrNoProgress
+//
+// pending skips are done first, by seeking if they
+// are at least as big as the buffer, and otherwise
+// by discarding the start of what is read
+func (r *Reader) read(b []byte) (int, error) {
+	if r.pending >= int64(cap(r.data)) {
+		r.seekPending()
+	}
+	for i := 0; ; {
+		n, err := r.r.Read(b)
+		if r.pending > 0 && n > 0 {
+			d := n
+			if int64(d) > r.pending {
+				d = int(r.pending)
+			}
+			r.pending -= int64(d)
+			n = copy(b, b[d:n])
+			if n == 0 && err == nil {
+				continue
+			}
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+		if i >= r.retries {
+			return 0, io.ErrNoProgress
+		}
+		i++
+	}
+}
+
+// seekPending seeks past the pending skip,
+// if the underlying reader can seek
+func (r *Reader) seekPending() {
+	if r.rs == nil {
 		return
 	}
-	r.data = r.data[:len(r.data)+a]
+	if _, err := r.rs.Seek(r.pending, io.SeekCurrent); err != nil {
+		// the seeker can't actually seek;
+		// read and discard from now on
+		r.rs = nil
+		return
+	}
+	r.pending = 0
+}
+
+// SetRetries sets the number of times in a row that
+// the Reader retries a read from the underlying reader
+// that returns neither data nor an error before failing
+// with [io.ErrNoProgress]. The default is 100.
+//
+// Errors from the underlying reader are returned once
+// and then cleared, rather than being remembered. When
+// a read fails with a transient error, like a timeout,
+// any data read up to that point remains buffered
+// (methods like Peek and Next do not advance the reader
+// when they fail), so the call can simply be retried.
+func (r *Reader) SetRetries(n int) {
+	r.retries = max(n, 0)
 }
 
 // pop error
@@ -160,7 +503,9 @@ func (r *Reader) Buffered() int { return len(r.data) - r.n }
 // BufferSize returns the total size of the buffer
 func (r *Reader) BufferSize() int { return cap(r.data) }
 
-// InputOffset returns the input stream byte offset of the current reader position
+// InputOffset returns the input stream byte offset of the current reader position.
+// If the underlying reader is an [io.Seeker], the offset starts
+// at its position when it was passed to the Reader.
 func (r *Reader) InputOffset() int64 { return r.inputOffset }
 
 // Peek returns the next 'n' buffered bytes,
@@ -174,11 +519,8 @@ func (r *Reader) Peek(n int) ([]byte, error) {
 	// we may need to realloc
 	// (the caller asked for more
 	// bytes than the size of the buffer)
-	if cap(r.data) < n {
-		old := r.data[r.n:]
-		r.data = make([]byte, n+r.buffered())
-		r.data = r.data[:copy(r.data, old)]
-		r.n = 0
+	if err := r.grow(n); err != nil {
+		return r.data[r.n:], r.wrapErr("Peek", n, err)
 	}
 
 	// keep filling until
@@ -190,7 +532,7 @@ func (r *Reader) Peek(n int) ([]byte, error) {
 
 	// we must have hit an error
 	if r.buffered() < n {
-		return r.data[r.n:], r.err()
+		return r.data[r.n:], r.wrapErr("Peek", n, r.err())
 	}
 
 	return r.data[r.n : r.n+n], nil
@@ -207,11 +549,8 @@ func (r *Reader) PeekByte() (b byte, err error) {
 
 func (r *Reader) peekByte() (byte, error) {
 	const n = 1
-	if cap(r.data) < n {
-		old := r.data[r.n:]
-		r.data = make([]byte, n+r.buffered())
-		r.data = r.data[:copy(r.data, old)]
-		r.n = 0
+	if err := r.grow(n); err != nil {
+		return 0, err
 	}
 
 	// keep filling until
@@ -228,14 +567,82 @@ func (r *Reader) peekByte() (byte, error) {
 	return r.data[r.n], nil
 }
 
+// keep returns the index of the first byte
+// in the buffer that must be retained
+func (r *Reader) keep() int {
+	if len(r.marks) > 0 {
+		return r.n - int(r.inputOffset-r.markOffset)
+	}
+	return r.n
+}
+
+// grow makes sure that the buffer
+// can hold at least 'n' bytes starting
+// at the read position. If shrinking is
+// enabled and the oversized buffer is no
+// longer needed, it also returns the buffer
+// to its original size.
+func (r *Reader) grow(n int) error {
+	if r.static {
+		return nil
+	}
+	// don't grow for data past the limit
+	if r.limited {
+		if rem := r.limit - r.inputOffset; int64(n) > rem {
+			n = int(max64(rem, 0))
+		}
+	}
+	k := r.keep()
+	need := r.n - k + n
+	if c := cap(r.data); need > c {
+		if r.maxBuf > 0 && need > r.maxBuf {
+			return ErrBufferLimit
+		}
+		// grow geometrically so that slowly
+		// increasing reads don't realloc every time
+		size := max(need, 2*c)
+		if r.maxBuf > 0 && size > r.maxBuf {
+			size = r.maxBuf
+		}
+		r.realloc(k, size)
+	} else {
+		r.shrinkBuf(n)
+	}
+	return nil
+}
+
+// shrinkBuf returns an oversized buffer to its
+// original size if shrinking is enabled and the
+// retained data plus 'n' more bytes will fit
+func (r *Reader) shrinkBuf(n int) {
+	if r.shrink && !r.static && cap(r.data) > r.bufSize {
+		if k := r.keep(); r.n-k+n <= r.bufSize && len(r.data)-k <= r.bufSize {
+			r.realloc(k, r.bufSize)
+		}
+	}
+}
+
+// realloc moves the data from r.data[k:]
+// into a new buffer with capacity 'size'
+func (r *Reader) realloc(k int, size int) {
+	old := r.data[k:]
+	r.data = make([]byte, size)
+	r.data = r.data[:copy(r.data, old)]
+	r.n -= k
+}
+
 // discard(n) discards up to 'n' buffered bytes, and
 // and returns the number of bytes discarded
 func (r *Reader) discard(n int) int {
 	inbuf := r.buffered()
 	if inbuf <= n {
-		r.n = 0
+		if len(r.marks) > 0 || r.static {
+			r.n = len(r.data)
+		} else {
+			r.n = 0
+			r.data = r.data[:0]
+		}
 		r.inputOffset += int64(inbuf)
-		r.data = r.data[:0]
 		return inbuf
 	}
 	r.n += n
@@ -247,36 +654,75 @@ func (r *Reader) discard(n int) int {
 // Returns the number of bytes skipped and any
 // errors encountered. It is analogous to Seek(n, 1).
 // If the underlying reader implements io.Seeker, then
-// that method will be used to skip forward.
+// that method will be used to skip forward, unless
+// there is an outstanding [Reader.Mark]; if seeking
+// fails, Skip falls back to reading and discarding
+// the data instead. Seeking is done lazily, when data
+// is next read, so that consecutive skips only need
+// one seek; skips shorter than the buffer are done by
+// discarding the start of the next read instead.
 //
 // If the reader encounters
 // an EOF before skipping 'n' bytes, it
 // returns [io.ErrUnexpectedEOF]. If the
 // underlying reader implements [io.Seeker], then
-// those rules apply instead. (Many implementations
+// those rules apply instead (many implementations
 // will not return [io.EOF] until the next call
-// to Read).
+// to Read), except for regular files, where skipping
+// past the end also returns [io.ErrUnexpectedEOF].
 func (r *Reader) Skip(n int) (int, error) {
 	if n < 0 {
-		return 0, os.ErrInvalid
+		return 0, r.wrapErr("Skip", n, os.ErrInvalid)
 	}
 
 	// discard some or all of the current buffer
 	skipped := r.discard(n)
 
-	// if we can Seek() through the remaining bytes, do that
-	if n > skipped && r.rs != nil {
-		nn, err := r.rs.Seek(int64(n-skipped), 1)
-		r.inputOffset += nn
-		return int(nn) + skipped, err
+	// if we can Seek() through the remaining bytes,
+	// do that once we actually need more data
+	if n > skipped && r.rs != nil && len(r.marks) == 0 {
+		dist := int64(n - skipped)
+		var err error
+		if r.limited && dist > r.remaining() {
+			dist = max64(r.remaining(), 0)
+			err = ErrLimitExceeded
+		}
+		// regular files know their size, so
+		// we can tell if we'd skip past the end
+		// (the size is only checked again when
+		// skipping past it, in case it has grown)
+		if r.inputOffset+dist > r.rsSize && r.inputOffset+dist > r.seekerSize() {
+			dist = max64(r.rsSize-r.inputOffset, 0)
+			err = io.ErrUnexpectedEOF
+		}
+		r.pending += dist
+		r.inputOffset += dist
+		skipped += int(dist)
+		return skipped, r.wrapErr("Skip", n, err)
 	}
 	// otherwise, keep filling the buffer
 	// and discarding it up to 'n'
+	r.shrinkBuf(1)
 	for skipped < n && r.state == nil {
 		r.more()
 		skipped += r.discard(n - skipped)
 	}
-	return skipped, r.noEOF()
+	return skipped, r.wrapErr("Skip", n, r.noEOF())
+}
+
+// Align skips forward to the next input
+// offset that is a multiple of 'n', using
+// [Reader.Skip], and returns the number of
+// bytes skipped.
+func (r *Reader) Align(n int) (int, error) {
+	if n <= 0 {
+		return 0, os.ErrInvalid
+	}
+	pad := int(int64(n)-r.inputOffset%int64(n)) % n
+	if pad == 0 {
+		return 0, nil
+	}
+	return r.Skip(pad)
 }
 
 // Next returns the next 'n' bytes in the stream.
@@ -301,11 +747,8 @@ func (r *Reader) Next(n int) (b []byte, err error) {
 
 func (r *Reader) next(n int) ([]byte, error) {
 	// in case the buffer is too small
-	if cap(r.data) < n {
-		old := r.data[r.n:]
-		r.data = make([]byte, n+r.buffered())
-		r.data = r.data[:copy(r.data, old)]
-		r.n = 0
+	if err := r.grow(n); err != nil {
+		return r.data[r.n:], r.wrapErr("Next", n, err)
 	}
 
 	// fill at least 'n' bytes
@@ -314,7 +757,7 @@ func (r *Reader) next(n int) ([]byte, error) {
 	}
 
 	if r.buffered() < n {
-		return r.data[r.n:], r.noEOF()
+		return r.data[r.n:], r.wrapErr("Next", n, r.noEOF())
 	}
 	out := r.data[r.n : r.n+n]
 	r.n += n
@@ -336,12 +779,13 @@ func (r *Reader) Read(b []byte) (int, error) {
 	// we have no buffered data; determine
 	// whether or not to buffer or call
 	// the underlying reader directly
-	if len(b) >= cap(r.data) {
-		n, r.state = r.r.Read(b)
+	if len(b) >= cap(r.data) && len(r.marks) == 0 {
+		n, r.state = r.readDirect(b)
 	} else {
+		r.shrinkBuf(1)
 		r.more()
-		n = copy(b, r.data)
-		r.n = n
+		n = copy(b, r.data[r.n:])
+		r.n += n
 	}
 	if n == 0 {
 		return 0, r.err()
@@ -352,6 +796,28 @@ func (r *Reader) Read(b []byte) (int, error) {
 	return n, nil
 }
 
+// readDirect reads from the underlying
+// reader into 'b', bypassing the (empty) buffer
+func (r *Reader) readDirect(b []byte) (int, error) {
+	if r.limited {
+		rem := r.remaining()
+		if rem <= 0 {
+			return 0, ErrLimitExceeded
+		}
+		if int64(len(b)) > rem {
+			b = b[:rem]
+		}
+	}
+	if r.static {
+		return 0, io.EOF
+	}
+	// the bytes before the read position
+	// are no longer contiguous with the input
+	r.data = r.data[:0]
+	r.n = 0
+	return r.read(b)
+}
+
 // ReadFull attempts to read len(b) bytes into
 // 'b'. It returns the number of bytes read into
 // 'b', and an error if it does not return len(b).
@@ -369,22 +835,26 @@ func (r *Reader) ReadFull(b []byte) (int, error) {
 			n += nn
 			r.n += nn
 			r.inputOffset += int64(nn)
-		} else if l-n > cap(r.data) {
-			nn, r.state = r.r.Read(b[n:])
+		} else if l-n > cap(r.data) && len(r.marks) == 0 {
+			nn, r.state = r.readDirect(b[n:])
 			n += nn
 			r.inputOffset += int64(nn)
 		} else {
+			r.shrinkBuf(1)
 			r.more()
 		}
 	}
 	if n < l {
-		return n, r.noEOF()
+		return n, r.wrapErr("ReadFull", l, r.noEOF())
 	}
 	return n, nil
 }
 
 // ReadByte implements [io.ByteReader].
 func (r *Reader) ReadByte() (byte, error) {
+	if r.buffered() < 1 {
+		r.shrinkBuf(1)
+	}
 	for r.buffered() < 1 && r.state == nil {
 		r.more()
 	}
@@ -398,37 +868,339 @@ func (r *Reader) ReadByte() (byte, error) {
 	return b, nil
 }
 
+// UnreadByte implements [io.ByteScanner].
+// It steps the reader position back by one
+// byte. It returns [bufio.ErrInvalidUnreadByte]
+// if the previous byte is no longer held in
+// the read buffer.
+func (r *Reader) UnreadByte() error {
+	if r.n == 0 {
+		return bufio.ErrInvalidUnreadByte
+	}
+	r.n--
+	r.inputOffset--
+	return nil
+}
+
+// ReadRune implements [io.RuneReader].
+// Invalid UTF-8 is returned as [utf8.RuneError]
+// with a size of 1.
+func (r *Reader) ReadRune() (rune, int, error) {
+	if r.buffered() < utf8.UTFMax {
+		r.shrinkBuf(utf8.UTFMax)
+	}
+	for r.buffered() < utf8.UTFMax && !utf8.FullRune(r.data[r.n:]) && r.state == nil {
+		r.more()
+	}
+	if r.buffered() < 1 {
+		return 0, 0, r.err()
+	}
+	c, size := rune(r.data[r.n]), 1
+	if c >= utf8.RuneSelf {
+		c, size = utf8.DecodeRune(r.data[r.n:])
+	}
+	r.n += size
+	r.inputOffset += int64(size)
+	r.lastRune = size
+	r.runeOffset = r.inputOffset
+	return c, size, nil
+}
+
+// UnreadRune implements [io.RuneScanner].
+// It returns [bufio.ErrInvalidUnreadRune] unless
+// the reader position has not moved since
+// the last call to ReadRune.
+func (r *Reader) UnreadRune() error {
+	if r.lastRune <= 0 || r.runeOffset != r.inputOffset || r.n < r.lastRune {
+		return bufio.ErrInvalidUnreadRune
+	}
+	r.n -= r.lastRune
+	r.inputOffset -= int64(r.lastRune)
+	r.lastRune = 0
+	return nil
+}
+
+// ReadSlice reads until the first occurrence of 'delim'
+// in the input and returns a slice pointing at the
+// bytes in the buffer up to and including the delimiter.
+// Like Peek, ReadSlice will re-allocate the read buffer
+// if the delimiter is not found within the current
+// buffer size, and like Next, the returned slice is only
+// valid until the next reader method call.
+//
+// If the stream ends before the delimiter is found,
+// ReadSlice returns the remaining buffered bytes along with
+// [io.ErrUnexpectedEOF], and the reader position is not
+// incremented. If there are no bytes left in the stream
+// at all, ReadSlice returns [io.EOF].
+func (r *Reader) ReadSlice(delim byte) ([]byte, error) {
+	scanned := 0 // bytes already searched
+	r.shrinkBuf(1)
+	for {
+		if i := bytes.IndexByte(r.data[r.n+scanned:], delim); i >= 0 {
+			i += scanned + 1
+			out := r.data[r.n : r.n+i]
+			r.n += i
+			r.inputOffset += int64(i)
+			return out, nil
+		}
+		scanned = r.buffered()
+		if r.state != nil {
+			if scanned == 0 {
+				return nil, r.err()
+			}
+			return r.data[r.n:], r.noEOF()
+		}
+		// if the buffer is full and there
+		// is no delimiter in it, more()
+		// will grow it
+		r.more()
+	}
+}
+
+// ReadBytes is like ReadSlice, but it returns
+// a copy of the bytes in the buffer, so the
+// returned slice remains valid after subsequent
+// reader method calls.
+func (r *Reader) ReadBytes(delim byte) ([]byte, error) {
+	b, err := r.ReadSlice(delim)
+	if b == nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), err
+}
+
+// ReadString is like ReadBytes, but it returns a string.
+func (r *Reader) ReadString(delim byte) (string, error) {
+	b, err := r.ReadSlice(delim)
+	return string(b), err
+}
+
+// ReadLine returns the next line of input, not
+// including the trailing "\n" or "\r\n". The
+// returned slice points into the read buffer, so
+// it is only valid until the next reader method call.
+// A final line without a trailing newline is
+// returned without an error. ReadLine returns [io.EOF]
+// only when there are no more bytes in the stream.
+func (r *Reader) ReadLine() ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err == io.ErrUnexpectedEOF {
+		// the last line didn't have
+		// a newline; consume it anyway
+		line, err = r.data[r.n:], nil
+		r.inputOffset += int64(len(line))
+		r.n = len(r.data)
+	} else if err != nil {
+		return line, err
+	} else {
+		line = line[:len(line)-1]
+	}
+	if l := len(line); l > 0 && line[l-1] == '\r' {
+		line = line[:l-1]
+	}
+	return line, err
+}
+
+var errSeekMarked = errors.New("fwd: cannot seek outside the buffer with an outstanding mark")
+
+// Seek implements [io.Seeker].
+// Seeks to a position within the buffered
+// window (including bytes that have already
+// been read, but not yet discarded from the buffer)
+// are satisfied without touching the underlying
+// reader. Otherwise, the buffer is discarded and
+// the underlying [io.Seeker] is used. If the underlying
+// reader is not an [io.Seeker], only forward seeks
+// are supported, and they are performed with [Reader.Skip].
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = r.inputOffset + offset
+	case io.SeekEnd:
+		target = -1
+		if r.static {
+			// data[0] is at offset inputOffset-n,
+			// and the input ends with the buffer
+			size := r.inputOffset - int64(r.n) + int64(len(r.data)+r.hidden)
+			target, whence = size+offset, io.SeekStart
+		}
+	default:
+		return r.inputOffset, os.ErrInvalid
+	}
+
+	if whence != io.SeekEnd {
+		if target < 0 {
+			return r.inputOffset, os.ErrInvalid
+		}
+		// offset of r.data[0]
+		base := r.inputOffset - int64(r.n)
+		if len(r.marks) > 0 && target < r.markOffset {
+			// the data before the oldest
+			// mark is not retained
+			return r.inputOffset, errSeekMarked
+		}
+		if target >= base && target <= base+int64(len(r.data)) {
+			r.n = int(target - base)
+			r.inputOffset = target
+			return target, nil
+		}
+	}
+
+	if r.rs == nil {
+		if target < r.inputOffset {
+			return r.inputOffset, os.ErrInvalid
+		}
+		_, err := r.Skip(int(target - r.inputOffset))
+		return r.inputOffset, err
+	}
+	if len(r.marks) > 0 {
+		return r.inputOffset, errSeekMarked
+	}
+	var pos int64
+	var err error
+	if whence == io.SeekEnd {
+		pos, err = r.rs.Seek(offset, io.SeekEnd)
+	} else {
+		pos, err = r.rs.Seek(target, io.SeekStart)
+	}
+	if err != nil {
+		return r.inputOffset, err
+	}
+	r.data = r.data[:0]
+	r.n = 0
+	r.state = nil
+	r.excess = r.excess[:0]
+	r.hidden = 0
+	r.pending = 0
+	r.inputOffset = pos
+	return pos, nil
+}
+
+// Mark is a checkpoint in the input
+// stream returned by [Reader.Mark].
+type Mark struct {
+	offset int64
+	id     uint64
+}
+
+// Offset returns the input stream offset of the mark.
+func (m Mark) Offset() int64 { return m.offset }
+
+// ErrInvalidMark is returned by [Reader.Rewind]
+// and [Reader.Release] when the mark is not held
+// by the reader, e.g. because it has already
+// been released.
+var ErrInvalidMark = errors.New("fwd: invalid mark")
+
+// Mark returns a checkpoint at the current
+// reader position. Until the mark is released
+// with [Reader.Release], the reader retains every
+// byte read after the mark in its buffer (growing
+// the buffer as necessary) so that [Reader.Rewind]
+// can return to it. Marks may be nested.
+func (r *Reader) Mark() Mark {
+	if len(r.marks) == 0 {
+		r.markOffset = r.inputOffset
+	}
+	r.markID++
+	r.marks = append(r.marks, r.markID)
+	return Mark{offset: r.inputOffset, id: r.markID}
+}
+
+// markIndex returns the index of the mark
+// with the given id in r.marks, or -1
+func (r *Reader) markIndex(id uint64) int {
+	for i := range r.marks {
+		if r.marks[i] == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Rewind restores the reader position (and
+// the input offset) to the position of 'm'.
+// The mark remains outstanding, so Rewind
+// may be called again with the same mark.
+func (r *Reader) Rewind(m Mark) error {
+	if r.markIndex(m.id) < 0 || m.offset < r.markOffset {
+		return ErrInvalidMark
+	}
+	i := r.n + int(m.offset-r.inputOffset)
+	if i > len(r.data) {
+		return ErrInvalidMark
+	}
+	r.n = i
+	r.inputOffset = m.offset
+	return nil
+}
+
+// Release releases the mark 'm'. Once every
+// outstanding mark is released, the reader
+// no longer retains data that has already been read.
+// Releasing a mark that is not held by the reader
+// returns [ErrInvalidMark].
+func (r *Reader) Release(m Mark) error {
+	i := r.markIndex(m.id)
+	if i < 0 {
+		return ErrInvalidMark
+	}
+	r.marks = append(r.marks[:i], r.marks[i+1:]...)
+	return nil
+}
+
 // WriteTo implements [io.WriterTo].
+// Once the buffered data has been written,
+// WriteTo hands off to the underlying reader's
+// WriteTo method or the writer's ReadFrom method,
+// if either is available, so that the copy can
+// take advantage of their fast paths (e.g. sendfile).
+// If 'w' is a [*Writer], it is flushed, and the
+// data is written directly to its underlying writer.
 func (r *Reader) WriteTo(w io.Writer) (int64, error) {
 	var (
 		i   int64
 		ii  int
 		err error
 	)
+	if fw, ok := w.(*Writer); ok && len(fw.holds) == 0 {
+		if err = fw.Flush(); err != nil {
+			return 0, err
+		}
+		i, err = r.WriteTo(fw.w)
+		fw.written += i
+		return i, err
+	}
 	// first, clear buffer
 	if r.buffered() > 0 {
 		ii, err = w.Write(r.data[r.n:])
 		i += int64(ii)
+		r.discard(ii)
 		if err != nil {
 			return i, err
 		}
-		r.data = r.data[0:0]
-		r.n = 0
-		r.inputOffset += int64(ii)
 	}
+	if r.state == nil && len(r.marks) == 0 && !r.static {
+		if nn, ok, err := r.writeToDirect(w); ok {
+			return i + nn, err
+		}
+	}
+	r.shrinkBuf(1)
 	for r.state == nil {
 		// here we just do
 		// 1:1 reads and writes
 		r.more()
 		if r.buffered() > 0 {
-			ii, err = w.Write(r.data)
+			ii, err = w.Write(r.data[r.n:])
 			i += int64(ii)
+			r.discard(ii)
 			if err != nil {
 				return i, err
 			}
-			r.data = r.data[0:0]
-			r.n = 0
-			r.inputOffset += int64(ii)
 		}
 	}
 	if r.state != io.EOF {
@@ -437,9 +1209,52 @@ func (r *Reader) WriteTo(w io.Writer) (int64, error) {
 	return i, nil
 }
 
+// writeToDirect copies the rest of the input
+// to 'w' without using the buffer, if the underlying
+// reader implements io.WriterTo or 'w' implements
+// io.ReaderFrom. The buffer must be empty.
+func (r *Reader) writeToDirect(w io.Writer) (n int64, ok bool, err error) {
+	if r.pending > 0 {
+		if r.seekPending(); r.pending > 0 {
+			return 0, false, nil
+		}
+	}
+	if r.limited {
+		// ReadFrom implementations like (*net.TCPConn).ReadFrom
+		// know how to handle an *io.LimitedReader
+		rf, ok := w.(io.ReaderFrom)
+		if !ok {
+			return 0, false, nil
+		}
+		lr := &io.LimitedReader{R: r.r, N: max64(r.remaining(), 0)}
+		n, err = rf.ReadFrom(lr)
+		r.inputOffset += n
+		if err == nil && lr.N == 0 {
+			err = ErrLimitExceeded
+		}
+		return n, true, err
+	}
+	if wt, ok := r.r.(io.WriterTo); ok {
+		n, err = wt.WriteTo(w)
+	} else if rf, ok := w.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r.r)
+	} else {
+		return 0, false, nil
+	}
+	r.inputOffset += n
+	return n, true, err
+}
+
 func max(a int, b int) int {
 	if a < b {
 		return b
 	}
 	return a
 }
+
+func max64(a int64, b int64) int64 {
+	if a < b {
+		return b
+	}
+	return a
+}
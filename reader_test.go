@@ -2,10 +2,14 @@ package fwd
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"os"
+	"strings"
 	"testing"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -23,10 +27,15 @@ func (p partialReader) Read(b []byte) (int, error) {
 
 func randomBts(sz int) []byte {
 	o := make([]byte, sz)
-	for i := 0; i < len(o); i += 8 {
+	i := 0
+	for ; i+8 <= len(o); i += 8 {
 		j := (*int64)(unsafe.Pointer(&o[i]))
 		*j = rand.Int63()
 	}
+	// don't write past the end of 'o'
+	for ; i < len(o); i++ {
+		o[i] = byte(rand.Int())
+	}
 	return o
 }
 
@@ -561,3 +570,825 @@ func TestReaderBufCreation(t *testing.T) {
 		}
 	}
 }
+
+func TestReadSlice(t *testing.T) {
+	var bts []byte
+	var lines [][]byte
+	for i := 0; i < 50; i++ {
+		line := append(randomBts(rand.Intn(300)), '\n')
+		for j := range line[:len(line)-1] {
+			if line[j] == '\n' {
+				line[j] = 'x'
+			}
+		}
+		lines = append(lines, line)
+		bts = append(bts, line...)
+	}
+	bts = append(bts, "trailing"...)
+
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+	var off int64
+	for i, line := range lines {
+		out, err := rd.ReadSlice('\n')
+		if err != nil {
+			t.Fatalf("line %d: %s", i, err)
+		}
+		if !bytes.Equal(out, line) {
+			t.Fatalf("line %d: lines not equal", i)
+		}
+		off += int64(len(line))
+		if rd.InputOffset() != off {
+			t.Fatalf("line %d: expected offset %d; got %d", i, off, rd.InputOffset())
+		}
+	}
+
+	// no delimiter before EOF
+	out, err := rd.ReadSlice('\n')
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %q", io.ErrUnexpectedEOF, err)
+	}
+	if string(out) != "trailing" {
+		t.Fatalf("expected %q; got %q", "trailing", out)
+	}
+	if rd.InputOffset() != off {
+		t.Fatalf("expected offset %d; got %d", off, rd.InputOffset())
+	}
+	s, err := rd.ReadString('g')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "trailing" {
+		t.Fatalf("expected %q; got %q", "trailing", s)
+	}
+	_, err = rd.ReadBytes('\n')
+	if err != io.EOF {
+		t.Fatalf("expected %q; got %q", io.EOF, err)
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	rd := NewReaderSize(partialReader{bytes.NewReader([]byte("one\r\ntwo\n\nthree"))}, 16)
+	for _, want := range []string{"one", "two", "", "three"} {
+		line, err := rd.ReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(line) != want {
+			t.Fatalf("expected %q; got %q", want, line)
+		}
+	}
+	if _, err := rd.ReadLine(); err != io.EOF {
+		t.Fatalf("expected %q; got %q", io.EOF, err)
+	}
+	if rd.InputOffset() != 15 {
+		t.Fatalf("expected offset 15; got %d", rd.InputOffset())
+	}
+}
+
+func TestUnreadByte(t *testing.T) {
+	bts := randomBts(512)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 16)
+
+	if err := rd.UnreadByte(); err == nil {
+		t.Fatal("expected an error unreading at offset 0")
+	}
+	for i := range bts {
+		b, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rd.UnreadByte(); err != nil {
+			t.Fatalf("offset %d: %s", i, err)
+		}
+		if rd.InputOffset() != int64(i) {
+			t.Fatalf("expected offset %d; got %d", i, rd.InputOffset())
+		}
+		c, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != c || c != bts[i] {
+			t.Fatalf("offset %d: %d in; %d and %d out", i, bts[i], b, c)
+		}
+	}
+}
+
+func TestReadRune(t *testing.T) {
+	str := strings.Repeat("aé€😀\xff", 40)
+	rd := NewReaderSize(partialReader{strings.NewReader(str)}, 16)
+
+	var out []rune
+	for {
+		c, size, err := rd.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != utf8.RuneError && size != utf8.RuneLen(c) {
+			t.Fatalf("rune %q has size %d; got %d", c, utf8.RuneLen(c), size)
+		}
+		if err := rd.UnreadRune(); err != nil {
+			t.Fatal(err)
+		}
+		if err := rd.UnreadRune(); err == nil {
+			t.Fatal("expected an error unreading a rune twice")
+		}
+		d, _, err := rd.ReadRune()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != d {
+			t.Fatalf("read %q after unreading %q", d, c)
+		}
+		out = append(out, c)
+	}
+	if want := []rune(str); string(out) != string(want) {
+		t.Fatalf("expected %q; got %q", string(want), string(out))
+	}
+	if rd.InputOffset() != int64(len(str)) {
+		t.Fatalf("expected offset %d; got %d", len(str), rd.InputOffset())
+	}
+}
+
+func TestMarkRewind(t *testing.T) {
+	bts := randomBts(4096)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+
+	rd.Next(10)
+	m := rd.Mark()
+	if m.Offset() != 10 {
+		t.Fatalf("expected mark at offset 10; got %d", m.Offset())
+	}
+
+	// read well past the size of the buffer
+	out, err := rd.Next(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[10:110]) {
+		t.Fatal("bytes not equal")
+	}
+	inner := rd.Mark()
+	big := make([]byte, 1000)
+	if _, err := rd.ReadFull(big); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rd.Skip(500); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rd.Rewind(inner); err != nil {
+		t.Fatal(err)
+	}
+	if rd.InputOffset() != 110 {
+		t.Fatalf("expected offset 110; got %d", rd.InputOffset())
+	}
+	if err := rd.Rewind(m); err != nil {
+		t.Fatal(err)
+	}
+	if rd.InputOffset() != 10 {
+		t.Fatalf("expected offset 10; got %d", rd.InputOffset())
+	}
+	out, err = rd.Next(1600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[10:1610]) {
+		t.Fatal("bytes not equal after rewind")
+	}
+	if err := rd.Release(inner); err != nil {
+		t.Fatal(err)
+	}
+	// releasing a mark twice doesn't
+	// release another one
+	if err := rd.Release(inner); err != ErrInvalidMark {
+		t.Fatalf("expected %q; got %q", ErrInvalidMark, err)
+	}
+	if err := rd.Rewind(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.Release(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.Rewind(m); err != ErrInvalidMark {
+		t.Fatalf("expected %q; got %q", ErrInvalidMark, err)
+	}
+
+	// a mark must disable the Seek fast path in Skip
+	rd = NewReaderSize(bytes.NewReader(bts), 64)
+	m = rd.Mark()
+	if _, err := rd.Skip(1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.Rewind(m); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, bts) {
+		t.Fatal("bytes not equal after rewinding a skip")
+	}
+}
+
+func TestSeek(t *testing.T) {
+	bts := randomBts(2048)
+	br := bytes.NewReader(bts)
+	br.Seek(100, io.SeekStart)
+
+	rd := NewReaderSize(br, 128)
+	if rd.InputOffset() != 100 {
+		t.Fatalf("expected offset 100; got %d", rd.InputOffset())
+	}
+
+	check := func(off int64) {
+		t.Helper()
+		if rd.InputOffset() != off {
+			t.Fatalf("expected offset %d; got %d", off, rd.InputOffset())
+		}
+		b, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bts[off] {
+			t.Fatalf("offset %d: %d in; %d out", off, bts[off], b)
+		}
+		rd.UnreadByte()
+	}
+
+	rd.Next(50)
+	check(150)
+
+	// small backwards seek within the buffer
+	pos, err := rd.Seek(-20, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 130 {
+		t.Fatalf("expected position 130; got %d", pos)
+	}
+	check(130)
+
+	// absolute seek outside the buffer
+	if _, err := rd.Seek(1000, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	check(1000)
+	if _, err := rd.Seek(10, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	check(10)
+	pos, err = rd.Seek(-48, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 2000 {
+		t.Fatalf("expected position 2000; got %d", pos)
+	}
+	check(2000)
+	if _, err := rd.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("expected an error seeking to a negative offset")
+	}
+
+	// skip after an in-buffer seek
+	rd.Seek(500, io.SeekStart)
+	rd.Next(10)
+	n, err := rd.Skip(500)
+	if err != nil || n != 500 {
+		t.Fatalf("Skip(500) returned (%d, %v)", n, err)
+	}
+	check(1010)
+
+	// can't seek back past an outstanding mark
+	rd.Seek(0, io.SeekStart)
+	rd.Next(4)
+	m := rd.Mark()
+	rd.Next(4)
+	if _, err := rd.Seek(2, io.SeekStart); err == nil {
+		t.Fatal("expected an error seeking before a mark")
+	}
+	if _, err := rd.Peek(256); err != nil {
+		t.Fatal(err)
+	}
+	rd.Release(m)
+	check(8)
+
+	// non-seekers only support forward seeks
+	rd = NewReaderSize(partialReader{bytes.NewReader(bts)}, 128)
+	if _, err := rd.Seek(300, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	check(300)
+	if _, err := rd.Seek(0, io.SeekStart); err == nil {
+		t.Fatal("expected an error seeking backwards past the buffer")
+	}
+}
+
+func TestBufferLimit(t *testing.T) {
+	bts := randomBts(4096)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 64)
+	rd.SetBufferLimit(1024)
+
+	if _, err := rd.Peek(2048); err != ErrBufferLimit {
+		t.Fatalf("expected %q; got %q", ErrBufferLimit, err)
+	}
+	if rd.BufferSize() != 64 {
+		t.Fatalf("buffer grew to %d bytes", rd.BufferSize())
+	}
+	if _, err := rd.Next(1 << 30); err != ErrBufferLimit {
+		t.Fatalf("expected %q; got %q", ErrBufferLimit, err)
+	}
+
+	// slowly increasing peeks should
+	// grow the buffer geometrically
+	sizes := make(map[int]struct{})
+	for i := 65; i <= 1024; i++ {
+		if _, err := rd.Peek(i); err != nil {
+			t.Fatal(err)
+		}
+		sizes[rd.BufferSize()] = struct{}{}
+	}
+	if len(sizes) > 5 {
+		t.Errorf("buffer was reallocated %d times", len(sizes))
+	}
+	if rd.BufferSize() != 1024 {
+		t.Errorf("expected buffer size 1024; got %d", rd.BufferSize())
+	}
+
+	// ReadSlice respects the limit, too
+	rd = NewReaderSize(strings.NewReader(strings.Repeat("a", 4096)), 64)
+	rd.SetBufferLimit(1024)
+	if _, err := rd.ReadSlice('\n'); err != ErrBufferLimit {
+		t.Fatalf("expected %q; got %q", ErrBufferLimit, err)
+	}
+}
+
+func TestBufferShrink(t *testing.T) {
+	bts := randomBts(8192)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 128)
+	rd.SetBufferShrink(true)
+
+	if _, err := rd.Next(1000); err != nil {
+		t.Fatal(err)
+	}
+	if rd.BufferSize() < 1000 {
+		t.Fatalf("expected buffer to grow; size is %d", rd.BufferSize())
+	}
+	// drain whatever is left in the buffer
+	rd.Next(rd.Buffered())
+
+	out, err := rd.Next(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[rd.InputOffset()-10:rd.InputOffset()]) {
+		t.Fatal("bytes not equal")
+	}
+	if rd.BufferSize() != 128 {
+		t.Fatalf("expected buffer to shrink to 128 bytes; size is %d", rd.BufferSize())
+	}
+
+	// without shrinking enabled, the buffer stays large
+	rd = NewReaderSize(partialReader{bytes.NewReader(bts)}, 128)
+	rd.Next(1000)
+	rd.Next(rd.Buffered())
+	rd.ReadByte()
+	if rd.BufferSize() < 1000 {
+		t.Fatalf("buffer shrank to %d bytes", rd.BufferSize())
+	}
+}
+
+func TestLimit(t *testing.T) {
+	bts := randomBts(4096)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts)}, 128)
+
+	// buffer some data past the limit
+	rd.Peek(100)
+	rd.Next(10)
+	rd.SetLimit(50)
+
+	if _, err := rd.Peek(51); err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+	out, err := rd.Next(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[10:60]) {
+		t.Fatal("bytes not equal")
+	}
+	if _, err := rd.ReadByte(); err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+	if _, err := rd.Read(make([]byte, 10)); err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+	rd.ClearLimit()
+	b, err := rd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != bts[60] {
+		t.Fatalf("offset 60: %d in; %d out", bts[60], b)
+	}
+
+	// limit to an absolute offset
+	rd.SetLimit(1000 - rd.InputOffset())
+	n, err := rd.ReadFull(make([]byte, 2000))
+	if err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+	if n != 1000-61 {
+		t.Fatalf("expected to read %d bytes; read %d", 1000-61, n)
+	}
+	if rd.InputOffset() != 1000 {
+		t.Fatalf("expected offset 1000; got %d", rd.InputOffset())
+	}
+	if _, err := rd.Skip(1); err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+
+	// no allocation for a hostile Peek
+	rd.SetLimit(100)
+	if _, err := rd.Peek(1 << 30); err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+	var w bytes.Buffer
+	nw, err := rd.WriteTo(&w)
+	if err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+	if nw != 100 || !bytes.Equal(w.Bytes(), bts[1000:1100]) {
+		t.Fatalf("WriteTo wrote %d bytes", nw)
+	}
+	rd.ClearLimit()
+	rest, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, bts[1100:]) {
+		t.Fatal("bytes not equal after clearing the limit")
+	}
+
+	// seekers
+	rd = NewReaderSize(bytes.NewReader(bts), 128)
+	rd.SetLimit(300)
+	n, err = rd.Skip(500)
+	if err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+	if n != 300 || rd.InputOffset() != 300 {
+		t.Fatalf("skipped %d bytes to offset %d", n, rd.InputOffset())
+	}
+
+	// static readers
+	rd = NewReaderBytes(bts[:100])
+	rd.SetLimit(10)
+	rd.Next(10)
+	if _, err := rd.Read(make([]byte, 200)); err != ErrLimitExceeded {
+		t.Fatalf("expected %q; got %q", ErrLimitExceeded, err)
+	}
+	// the data past the limit isn't copied
+	if cap(rd.excess) != 0 {
+		t.Fatalf("copied %d bytes past the limit", cap(rd.excess))
+	}
+	rd.ClearLimit()
+	rest, err = ioutil.ReadAll(rd)
+	if err != nil || !bytes.Equal(rest, bts[10:100]) {
+		t.Fatalf("bytes not equal after clearing the limit: %v", err)
+	}
+}
+
+func TestReaderBytes(t *testing.T) {
+	bts := randomBts(4096)
+	rd := NewReaderBytes(bts)
+
+	peek, err := rd.Peek(3000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &peek[0] != &bts[0] {
+		t.Fatal("Peek copied the input")
+	}
+	rd.SetBufferLimit(16)
+	out, err := rd.Next(4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &out[0] != &bts[0] || len(out) != 4000 {
+		t.Fatal("Next copied the input")
+	}
+	if _, err := rd.Next(100); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %q", io.ErrUnexpectedEOF, err)
+	}
+	pos, err := rd.Seek(-10, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 4086 {
+		t.Fatalf("expected position 4086; got %d", pos)
+	}
+	rest, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, bts[4086:]) {
+		t.Fatal("bytes not equal")
+	}
+	if _, err := rd.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	line, err := rd.ReadSlice(bts[100])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &line[0] != &bts[0] {
+		t.Fatal("ReadSlice copied the input")
+	}
+
+	// Reset must not write into the input
+	cpy := append([]byte(nil), bts...)
+	rd.Reset(bytes.NewReader(randomBts(4096)))
+	ioutil.ReadAll(rd)
+	if !bytes.Equal(cpy, bts) {
+		t.Fatal("Reset wrote into the input")
+	}
+
+	// nor allocate a buffer the size of it
+	rd = NewReaderBytes(make([]byte, 1<<20))
+	rd.Reset(bytes.NewReader(nil))
+	if rd.BufferSize() != DefaultReaderSize {
+		t.Fatalf("Reset allocated a %d byte buffer", rd.BufferSize())
+	}
+}
+
+func TestReaderFile(t *testing.T) {
+	bts := randomBts(8192)
+	f, err := ioutil.TempFile(t.TempDir(), "fwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(bts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(100, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rd := NewReaderFile(f)
+	if rd.InputOffset() != 100 {
+		t.Fatalf("expected offset 100; got %d", rd.InputOffset())
+	}
+	out, err := rd.Next(5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, bts[100:5100]) {
+		t.Fatal("bytes not equal")
+	}
+	rest, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, bts[5100:]) {
+		t.Fatal("bytes not equal")
+	}
+	if err := rd.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAlign(t *testing.T) {
+	bts := randomBts(2048)
+	for _, rd := range []*Reader{
+		NewReaderSize(partialReader{bytes.NewReader(bts)}, 64),
+		NewReaderSize(bytes.NewReader(bts), 64),
+	} {
+		rd.Next(3)
+		n, err := rd.Align(8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 5 || rd.InputOffset() != 8 {
+			t.Fatalf("Align(8) skipped %d bytes to offset %d", n, rd.InputOffset())
+		}
+		if n, _ := rd.Align(8); n != 0 {
+			t.Fatalf("Align(8) at offset 8 skipped %d bytes", n)
+		}
+		rd.Next(1)
+		if _, err := rd.Align(512); err != nil {
+			t.Fatal(err)
+		}
+		b, err := rd.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b != bts[512] {
+			t.Fatalf("offset 512: %d in; %d out", bts[512], b)
+		}
+		if _, err := rd.Align(0); err == nil {
+			t.Fatal("expected an error for Align(0)")
+		}
+	}
+}
+
+// flakyReader returns a timeout error along with
+// every other chunk of data, and (0, nil) in between
+type flakyReader struct {
+	r     io.Reader
+	calls int
+}
+
+func (f *flakyReader) Read(b []byte) (int, error) {
+	f.calls++
+	switch f.calls % 3 {
+	case 0:
+		return 0, nil
+	case 1:
+		if len(b) > 5 {
+			b = b[:5]
+		}
+		n, err := f.r.Read(b)
+		if err == nil {
+			err = os.ErrDeadlineExceeded
+		}
+		return n, err
+	default:
+		return f.r.Read(b[:1])
+	}
+}
+
+func TestReaderResume(t *testing.T) {
+	bts := randomBts(1000)
+	rd := NewReaderSize(&flakyReader{r: bytes.NewReader(bts)}, 16)
+	var out []byte
+	for len(out) < len(bts) {
+		b, err := rd.Next(7)
+		if err == io.ErrUnexpectedEOF {
+			b, err = rd.Next(rd.Buffered())
+		}
+		if err != nil {
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Fatal(err)
+			}
+			continue
+		}
+		out = append(out, b...)
+	}
+	if !bytes.Equal(out, bts) {
+		t.Fatal("data lost or duplicated across timeouts")
+	}
+
+	// empty reads are retried up to a point
+	rd = NewReader(&flakyReader{r: bytes.NewReader(nil), calls: 2})
+	if _, err := rd.ReadByte(); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+	rd = NewReader(&flakyReader{r: bytes.NewReader(nil), calls: 2})
+	rd.SetRetries(0)
+	if _, err := rd.ReadByte(); err != io.ErrNoProgress {
+		t.Fatalf("expected io.ErrNoProgress; got %v", err)
+	}
+
+	// including after resetting a NewReaderBytes reader
+	rd = NewReaderBytes(nil)
+	rd.Reset(&flakyReader{r: bytes.NewReader(nil), calls: 2})
+	if _, err := rd.ReadByte(); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+}
+
+// brokenSeeker reports its position,
+// but can't seek anywhere else
+type brokenSeeker struct {
+	io.Reader
+}
+
+func (b brokenSeeker) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekCurrent {
+		return 0, nil
+	}
+	return 0, errors.New("brokenSeeker: can't seek")
+}
+
+func TestSkipSeekFallback(t *testing.T) {
+	bts := randomBts(1024)
+	rd := NewReaderSize(brokenSeeker{bytes.NewReader(bts)}, 64)
+	if n, err := rd.Skip(500); n != 500 || err != nil {
+		t.Fatalf("Skip(500) = %d, %v", n, err)
+	}
+	if b, _ := rd.ReadByte(); b != bts[500] {
+		t.Fatalf("offset 500: %d in; %d out", bts[500], b)
+	}
+	if n, err := rd.Skip(1000); n != 1024-501 || err != io.ErrUnexpectedEOF {
+		t.Fatalf("Skip(1000) = %d, %v", n, err)
+	}
+
+	// pipes are *os.Files, but can't seek
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	go func() {
+		pw.Write(bts)
+		pw.Close()
+	}()
+	rd = NewReaderSize(pr, 64)
+	if n, err := rd.Skip(500); n != 500 || err != nil {
+		t.Fatalf("Skip(500) = %d, %v", n, err)
+	}
+	if b, _ := rd.ReadByte(); b != bts[500] {
+		t.Fatalf("offset 500: %d in; %d out", bts[500], b)
+	}
+
+	// regular files detect skips past the end
+	f, err := ioutil.TempFile(t.TempDir(), "fwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Write(bts)
+	f.Seek(0, io.SeekStart)
+	rd = NewReaderSize(f, 64)
+	rd.Next(10)
+	if n, err := rd.Skip(2000); n != 1014 || err != io.ErrUnexpectedEOF {
+		t.Fatalf("Skip(2000) = %d, %v", n, err)
+	}
+	if rd.InputOffset() != 1024 {
+		t.Fatalf("expected offset 1024; got %d", rd.InputOffset())
+	}
+	if _, err := rd.ReadByte(); err != io.EOF {
+		t.Fatalf("expected io.EOF; got %v", err)
+	}
+}
+
+// seekCounter counts calls to Seek
+type seekCounter struct {
+	*bytes.Reader
+	seeks int
+}
+
+func (s *seekCounter) Seek(offset int64, whence int) (int64, error) {
+	s.seeks++
+	return s.Reader.Seek(offset, whence)
+}
+
+func TestSkipLazy(t *testing.T) {
+	bts := randomBts(4096)
+	sc := &seekCounter{Reader: bytes.NewReader(bts)}
+	rd := NewReaderSize(sc, 64)
+	sc.seeks = 0
+
+	// consecutive skips are coalesced
+	// into a single seek
+	for i := 0; i < 5; i++ {
+		if n, err := rd.Skip(100); n != 100 || err != nil {
+			t.Fatalf("Skip(100) = %d, %v", n, err)
+		}
+		if want := int64(100 * (i + 1)); rd.InputOffset() != want {
+			t.Fatalf("expected offset %d; got %d", want, rd.InputOffset())
+		}
+	}
+	if sc.seeks != 0 {
+		t.Fatalf("%d seeks before reading", sc.seeks)
+	}
+	b, err := rd.ReadByte()
+	if err != nil || b != bts[500] {
+		t.Fatalf("offset 500: %d in; %d out (%v)", bts[500], b, err)
+	}
+	if sc.seeks != 1 {
+		t.Fatalf("expected 1 seek; got %d", sc.seeks)
+	}
+
+	// skips smaller than the buffer
+	// discard data instead of seeking
+	off := 501
+	for off < 3000 {
+		rd.Skip(rd.Buffered())
+		rd.Skip(10)
+		off = int(rd.InputOffset())
+		b, err := rd.ReadByte()
+		if err != nil || b != bts[off] {
+			t.Fatalf("offset %d: %d in; %d out (%v)", off, bts[off], b, err)
+		}
+	}
+	if sc.seeks != 1 {
+		t.Fatalf("expected 1 seek; got %d", sc.seeks)
+	}
+
+	// other reads see the pending skip
+	rd.Skip(rd.Buffered())
+	rd.Skip(200)
+	off = int(rd.InputOffset())
+	rest, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, bts[off:]) {
+		t.Fatal("bytes not equal")
+	}
+}
@@ -0,0 +1,81 @@
+package fwd
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// VarintOverflowError is returned by [Reader.ReadUvarint]
+// and [Reader.ReadVarint] when a varint in the input
+// does not fit in 64 bits.
+type VarintOverflowError struct {
+	Offset int64 // input offset of the start of the varint
+}
+
+func (e *VarintOverflowError) Error() string {
+	return "fwd: varint at input offset " + strconv.FormatInt(e.Offset, 10) + " overflows a 64-bit integer"
+}
+
+// ReadUvarint reads an unsigned varint (unsigned LEB128),
+// as encoded by [binary.PutUvarint], decoding it directly
+// from the read buffer. If the stream ends before any bytes
+// are read, ReadUvarint returns [io.EOF]; if it ends partway
+// through the varint, it returns [io.ErrUnexpectedEOF]. If the
+// varint overflows a uint64, it returns a *[VarintOverflowError].
+// The reader position is only advanced if ReadUvarint succeeds.
+func (r *Reader) ReadUvarint() (uint64, error) {
+	for {
+		v, n := binary.Uvarint(r.data[r.n:])
+		if n > 0 {
+			r.n += n
+			r.inputOffset += int64(n)
+			return v, nil
+		}
+		if n < 0 {
+			return 0, &VarintOverflowError{Offset: r.inputOffset}
+		}
+		// the varint continues past
+		// the end of the buffer
+		if r.state != nil {
+			if r.buffered() == 0 {
+				return 0, r.err()
+			}
+			return 0, r.noEOF()
+		}
+		r.more()
+	}
+}
+
+// ReadVarint reads a signed, zig-zag encoded varint,
+// as encoded by [binary.PutVarint]. Errors are handled
+// in the same way as [Reader.ReadUvarint].
+func (r *Reader) ReadVarint() (int64, error) {
+	ux, err := r.ReadUvarint()
+	x := int64(ux >> 1)
+	if ux&1 != 0 {
+		x = ^x
+	}
+	return x, err
+}
+
+// WriteUvarint writes 'v' as an unsigned varint
+// (unsigned LEB128), as encoded by [binary.PutUvarint].
+func (w *Writer) WriteUvarint(v uint64) error {
+	b, err := w.fixed(binary.MaxVarintLen64)
+	if err != nil {
+		return err
+	}
+	n := binary.PutUvarint(b, v)
+	w.buf = w.buf[:len(w.buf)-len(b)+n]
+	return nil
+}
+
+// WriteVarint writes 'v' as a signed, zig-zag encoded
+// varint, as encoded by [binary.PutVarint].
+func (w *Writer) WriteVarint(v int64) error {
+	ux := uint64(v) << 1
+	if v < 0 {
+		ux = ^ux
+	}
+	return w.WriteUvarint(ux)
+}
@@ -0,0 +1,75 @@
+package fwd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestVarint(t *testing.T) {
+	values := []int64{0, 1, -1, 63, -64, 64, 300, -300, math.MaxInt32, math.MinInt32, math.MaxInt64, math.MinInt64}
+
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 16)
+	var want []byte
+	for _, v := range values {
+		if err := wr.WriteVarint(v); err != nil {
+			t.Fatal(err)
+		}
+		if err := wr.WriteUvarint(uint64(v)); err != nil {
+			t.Fatal(err)
+		}
+		want = binary.AppendVarint(want, v)
+		want = binary.AppendUvarint(want, uint64(v))
+	}
+	wr.Flush()
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatal("encoding doesn't match encoding/binary")
+	}
+
+	rd := NewReaderSize(partialReader{bytes.NewReader(want)}, 16)
+	for _, v := range values {
+		x, err := rd.ReadVarint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x != v {
+			t.Fatalf("expected %d; got %d", v, x)
+		}
+		ux, err := rd.ReadUvarint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ux != uint64(v) {
+			t.Fatalf("expected %d; got %d", uint64(v), ux)
+		}
+	}
+	if rd.InputOffset() != int64(len(want)) {
+		t.Fatalf("expected offset %d; got %d", len(want), rd.InputOffset())
+	}
+	if _, err := rd.ReadUvarint(); err != io.EOF {
+		t.Fatalf("expected %q; got %v", io.EOF, err)
+	}
+
+	// truncated
+	rd = NewReaderBytes([]byte{0x80, 0x80})
+	if _, err := rd.ReadUvarint(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %q; got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	// overflow
+	bad := append([]byte{1, 2, 3}, bytes.Repeat([]byte{0xff}, 11)...)
+	rd = NewReaderSize(partialReader{bytes.NewReader(bad)}, 16)
+	rd.Next(3)
+	_, err := rd.ReadUvarint()
+	var oe *VarintOverflowError
+	if !errors.As(err, &oe) {
+		t.Fatalf("expected a *VarintOverflowError; got %v", err)
+	}
+	if oe.Offset != 3 {
+		t.Fatalf("expected offset 3; got %d", oe.Offset)
+	}
+}
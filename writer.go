@@ -1,6 +1,11 @@
 package fwd
 
-import "io"
+import (
+	"errors"
+	"io"
+	"os"
+	"unicode/utf8"
+)
 
 const (
 	// DefaultWriterSize is the
@@ -14,6 +19,22 @@ const (
 type Writer struct {
 	w   io.Writer // writer
 	buf []byte    // 0:len(buf) is bufered data
+	err error     // first error from w
+
+	written int64 // bytes written to w
+
+	// buffered data held back from Flush,
+	// in ascending order of output offset
+	holds  []hold
+	holdID uint64 // id of the last hold placed
+
+	// buffer growth policy for NextGrow
+	bufSize int  // original buffer size
+	maxBuf  int  // maximum buffer size; 0 means no limit
+	shrink  bool // return to bufSize after flushing
+
+	detailed bool // wrap errors in *Error
+	retries  int  // empty reads to retry in ReadFrom
 }
 
 // NewWriter returns a new writer
@@ -24,8 +45,10 @@ func NewWriter(w io.Writer) *Writer {
 		return wr
 	}
 	return &Writer{
-		w:   w,
-		buf: make([]byte, 0, DefaultWriterSize),
+		w:       w,
+		buf:     make([]byte, 0, DefaultWriterSize),
+		bufSize: DefaultWriterSize,
+		retries: defaultRetries,
 	}
 }
 
@@ -49,11 +72,110 @@ func NewWriterBuf(w io.Writer, buf []byte) *Writer {
 	}
 	buf = buf[:0]
 	return &Writer{
-		w:   w,
-		buf: buf,
+		w:       w,
+		buf:     buf,
+		bufSize: cap(buf),
+		retries: defaultRetries,
 	}
 }
 
+// Reset discards any buffered data and
+// clears any error, and resets the writer
+// to write to 'wr'. The buffer is reused.
+func (w *Writer) Reset(wr io.Writer) {
+	w.w = wr
+	w.buf = w.buf[:0]
+	w.err = nil
+	w.written = 0
+	w.holds = w.holds[:0]
+}
+
+// errHeld is returned by Close when some of the
+// buffered data could not be flushed because it
+// is still being held back
+var errHeld = errors.New("fwd: closed writer with unfinished held data")
+
+// Close flushes the writer, and then closes
+// the underlying writer if it implements [io.Closer].
+// It returns the first error encountered.
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if err == nil && len(w.holds) > 0 {
+		err = errHeld
+	}
+	if c, ok := w.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// SetBufferLimit sets the maximum size that
+// the write buffer may grow to in order to satisfy
+// calls to [Writer.NextGrow]. A limit of 0 or less
+// means that the buffer may grow without bound,
+// which is the default.
+func (w *Writer) SetBufferLimit(n int) {
+	w.maxBuf = max(n, 0)
+}
+
+// SetBufferShrink determines whether or not
+// the write buffer returns to its original size
+// once the data that required it to grow (see
+// [Writer.NextGrow]) has been flushed. By default,
+// the buffer never shrinks.
+func (w *Writer) SetBufferShrink(shrink bool) {
+	w.shrink = shrink
+}
+
+// SetRetries sets the number of times in a row that
+// [Writer.ReadFrom] retries a read that returns neither
+// data nor an error before failing with [io.ErrNoProgress].
+// The default is 100.
+//
+// Errors from the underlying writer are sticky, except
+// for transient errors like timeouts: when a write fails
+// with a transient error, whatever was not written remains
+// buffered (and nothing is written twice), so the call can
+// simply be retried.
+func (w *Writer) SetRetries(n int) {
+	w.retries = max(n, 0)
+}
+
+// OutputOffset returns the output stream
+// offset of the current write position, i.e.
+// the number of bytes written to the Writer
+// since it was created or last Reset.
+func (w *Writer) OutputOffset() int64 { return w.written + int64(len(w.buf)) }
+
+// Pad writes copies of 'fill' until the
+// output offset is a multiple of 'n'.
+func (w *Writer) Pad(n int, fill byte) error {
+	if n <= 0 {
+		return os.ErrInvalid
+	}
+	pad := int(int64(n)-w.OutputOffset()%int64(n)) % n
+	for pad > 0 {
+		k := w.Available()
+		if k == 0 {
+			k = cap(w.buf)
+		}
+		if k > pad {
+			k = pad
+		}
+		b, err := w.Next(k)
+		if err != nil {
+			return err
+		}
+		for i := range b {
+			b[i] = fill
+		}
+		pad -= k
+	}
+	return nil
+}
+
 // Buffered returns the number of buffered bytes
 // in the reader.
 func (w *Writer) Buffered() int { return len(w.buf) }
@@ -61,49 +183,309 @@ func (w *Writer) Buffered() int { return len(w.buf) }
 // BufferSize returns the maximum size of the buffer.
 func (w *Writer) BufferSize() int { return cap(w.buf) }
 
+// Size is an alias for BufferSize, for
+// compatibility with [bufio.Writer].
+func (w *Writer) Size() int { return cap(w.buf) }
+
+// Available returns the number of bytes
+// that are unused in the buffer.
+func (w *Writer) Available() int { return cap(w.buf) - len(w.buf) }
+
+// AvailableBuffer returns an empty slice with
+// Available() bytes of capacity that points to
+// the free space in the buffer. Bytes appended
+// to the slice can be added to the buffered data
+// without copying by calling [Writer.Advance] with
+// the number of bytes appended, or by passing
+// the slice to Write. The slice is only valid until
+// the next write operation.
+func (w *Writer) AvailableBuffer() []byte {
+	return w.buf[len(w.buf):len(w.buf)]
+}
+
+// Advance adds the next 'n' bytes of the free
+// space in the buffer (typically bytes appended
+// to the slice returned by [Writer.AvailableBuffer])
+// to the buffered data. It returns [io.ErrShortBuffer]
+// if 'n' is negative or greater than Available().
+func (w *Writer) Advance(n int) error {
+	if n < 0 || n > w.Available() {
+		return io.ErrShortBuffer
+	}
+	w.buf = w.buf[:len(w.buf)+n]
+	return nil
+}
+
 // Flush flushes any buffered bytes
 // to the underlying writer.
+//
+// Once the underlying writer returns an
+// error, that error is returned by every
+// subsequent call to Flush and to the methods
+// that write to the buffer, until [Writer.Reset]
+// is called.
 func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
 	l := len(w.buf)
+	if len(w.holds) > 0 {
+		// only flush up to the first held byte
+		l = int(w.holds[0].off - w.written)
+		if l > len(w.buf) {
+			l = len(w.buf)
+		}
+	}
 	if l > 0 {
-		n, err := w.w.Write(w.buf)
-
-		// if we didn't write the whole
-		// thing, copy the unwritten
-		// bytes to the beginnning of the
-		// buffer.
-		if n < l && n > 0 {
-			w.pushback(n)
-			if err == nil {
-				err = io.ErrShortWrite
-			}
+		n, err := w.w.Write(w.buf[:l])
+		w.written += int64(n)
+		if n < l && err == nil {
+			err = io.ErrShortWrite
 		}
 		if err != nil {
+			// if we didn't write the whole
+			// thing, copy the unwritten
+			// bytes to the beginnning of the
+			// buffer.
+			if n > 0 && n < l {
+				w.pushback(n)
+			}
+			err = w.wrapErr("Flush", l, err)
+			if !isTransient(err) {
+				w.err = err
+			}
 			return err
 		}
+		if l < len(w.buf) {
+			w.pushback(l)
+			return nil
+		}
 		w.buf = w.buf[:0]
+		if w.shrink && cap(w.buf) > w.bufSize {
+			w.buf = make([]byte, 0, w.bufSize)
+		}
 		return nil
 	}
 	return nil
 }
 
+// ErrInvalidReservation is returned by [Reservation.Fill]
+// when the reservation has already been filled, or
+// belongs to a Writer that has since been reset.
+var ErrInvalidReservation = errors.New("fwd: invalid reservation")
+
+// Reservation is a handle to a region of the
+// output reserved with [Writer.Reserve].
+type Reservation struct {
+	w   *Writer
+	off int64  // output offset of the region
+	n   int    // size of the region
+	id  uint64 // id of the hold on the region
+}
+
+// Reserve reserves the next 'n' bytes of the output
+// so that they can be filled in later with
+// [Reservation.Fill], e.g. with a length or checksum
+// of the data that follows. Until the reservation is
+// filled, Flush will not write the reserved bytes or
+// anything written after them; the write buffer grows
+// as necessary to hold that data, subject to the limit
+// set with [Writer.SetBufferLimit].
+func (w *Writer) Reserve(n int) (Reservation, error) {
+	b, err := w.NextGrow(n)
+	if err != nil {
+		return Reservation{}, err
+	}
+	for i := range b {
+		b[i] = 0
+	}
+	off := w.OutputOffset() - int64(n)
+	return Reservation{w: w, off: off, n: n, id: w.hold(off)}, nil
+}
+
+// Offset returns the output offset
+// of the reserved region.
+func (r Reservation) Offset() int64 { return r.off }
+
+// Len returns the size of the reserved region.
+func (r Reservation) Len() int { return r.n }
+
+// Fill fills in the reserved region with 'b',
+// which must be exactly as long as the region,
+// and allows the Writer to flush it.
+func (r Reservation) Fill(b []byte) error {
+	w := r.w
+	if w == nil || !w.holding(r.id) {
+		return ErrInvalidReservation
+	}
+	if len(b) != r.n {
+		return io.ErrShortBuffer
+	}
+	w.release(r.id)
+	if w.err != nil {
+		return w.err
+	}
+	copy(w.buf[r.off-w.written:], b)
+	return nil
+}
+
+// ErrInvalidSavepoint is returned by [Writer.Commit]
+// and [Writer.Rollback] when the savepoint has already
+// been committed or rolled back, or belongs to a
+// Writer that has since been reset.
+var ErrInvalidSavepoint = errors.New("fwd: invalid savepoint")
+
+// Savepoint is a position in the output
+// returned by [Writer.Begin].
+type Savepoint struct {
+	off int64  // output offset of the savepoint
+	id  uint64 // id of the hold at off
+}
+
+// Offset returns the output offset of the savepoint.
+func (s Savepoint) Offset() int64 { return s.off }
+
+// Begin starts a transaction at the current write
+// position. Everything written after it is held back
+// from Flush (with the write buffer growing as necessary,
+// subject to the limit set with [Writer.SetBufferLimit])
+// until the savepoint is either committed with
+// [Writer.Commit] or discarded with [Writer.Rollback].
+// Transactions may be nested.
+func (w *Writer) Begin() Savepoint {
+	off := w.OutputOffset()
+	return Savepoint{off: off, id: w.hold(off)}
+}
+
+// Commit commits the data written since
+// 's' was returned by [Writer.Begin], allowing
+// it to be flushed (unless it is still held back
+// by an enclosing transaction or a reservation).
+func (w *Writer) Commit(s Savepoint) error {
+	if !w.holding(s.id) {
+		return ErrInvalidSavepoint
+	}
+	w.release(s.id)
+	return nil
+}
+
+// Rollback discards everything written since 's' was
+// returned by [Writer.Begin], along with any savepoints,
+// reservations, and frames begun after it.
+func (w *Writer) Rollback(s Savepoint) error {
+	if !w.holding(s.id) {
+		return ErrInvalidSavepoint
+	}
+	w.discard(s.id)
+	return nil
+}
+
+// hold is a hold on the buffered data from output
+// offset 'off' onwards; ids are never reused, so
+// holds at the same offset can be told apart
+type hold struct {
+	off int64
+	id  uint64
+}
+
+// hold keeps the buffered data from output
+// offset 'off' onwards (which must be at or after
+// any existing hold) from being flushed until
+// release is called with the returned id; the
+// buffer grows as necessary to keep it
+func (w *Writer) hold(off int64) uint64 {
+	w.holdID++
+	w.holds = append(w.holds, hold{off: off, id: w.holdID})
+	return w.holdID
+}
+
+// release removes the hold with the given id
+func (w *Writer) release(id uint64) {
+	if i := w.holdIndex(id); i >= 0 {
+		w.holds = append(w.holds[:i], w.holds[i+1:]...)
+	}
+}
+
+// discard drops the buffered data held by the
+// hold with the given id, along with that hold
+// and every hold placed after it
+func (w *Writer) discard(id uint64) {
+	if i := w.holdIndex(id); i >= 0 {
+		w.buf = w.buf[:w.holds[i].off-w.written]
+		w.holds = w.holds[:i]
+	}
+}
+
+// holding returns whether or not
+// the hold with the given id is in place
+func (w *Writer) holding(id uint64) bool {
+	return w.holdIndex(id) >= 0
+}
+
+// holdIndex returns the index of the hold
+// with the given id in w.holds, or -1
+func (w *Writer) holdIndex(id uint64) int {
+	for i := range w.holds {
+		if w.holds[i].id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// flushFor makes room in the buffer for 'n'
+// more bytes by flushing it; if the buffered data
+// is being held back, the buffer grows instead
+func (w *Writer) flushFor(n int) error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if cap(w.buf)-len(w.buf) < n {
+		return w.grow(n)
+	}
+	return nil
+}
+
+// direct writes 'p' to the
+// underlying writer, bypassing the buffer
+func (w *Writer) direct(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	if n < len(p) && err == nil {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		err = w.wrapErr("Write", len(p), err)
+		if !isTransient(err) {
+			w.err = err
+		}
+	}
+	return n, err
+}
+
 // Write implements `io.Writer`
 func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
 	c, l, ln := cap(w.buf), len(w.buf), len(p)
 	avail := c - l
 
 	// requires flush
 	if avail < ln {
-		if err := w.Flush(); err != nil {
+		// too big to fit in buffer;
+		// write directly to w.w
+		if c < ln && len(w.holds) == 0 {
+			if err := w.Flush(); err != nil {
+				return 0, err
+			}
+			return w.direct(p)
+		}
+		if err := w.flushFor(ln); err != nil {
 			return 0, err
 		}
 		l = len(w.buf)
 	}
-	// too big to fit in buffer;
-	// write directly to w.w
-	if c < ln {
-		return w.w.Write(p)
-	}
 
 	// grow buf slice; copy; return
 	w.buf = w.buf[:l+ln]
@@ -112,33 +494,39 @@ func (w *Writer) Write(p []byte) (int, error) {
 
 // WriteString is analogous to Write, but it takes a string.
 func (w *Writer) WriteString(s string) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
 	c, l, ln := cap(w.buf), len(w.buf), len(s)
 	avail := c - l
 
 	// requires flush
 	if avail < ln {
-		if err := w.Flush(); err != nil {
+		// too big to fit in buffer;
+		// write directly to w.w
+		//
+		// yes, this is unsafe. *but*
+		// io.Writer is not allowed
+		// to mutate its input or
+		// maintain a reference to it,
+		// per the spec in package io.
+		//
+		// plus, if the string is really
+		// too big to fit in the buffer, then
+		// creating a copy to write it is
+		// expensive (and, strictly speaking,
+		// unnecessary)
+		if c < ln && len(w.holds) == 0 {
+			if err := w.Flush(); err != nil {
+				return 0, err
+			}
+			return w.direct(unsafestr(s))
+		}
+		if err := w.flushFor(ln); err != nil {
 			return 0, err
 		}
 		l = len(w.buf)
 	}
-	// too big to fit in buffer;
-	// write directly to w.w
-	//
-	// yes, this is unsafe. *but*
-	// io.Writer is not allowed
-	// to mutate its input or
-	// maintain a reference to it,
-	// per the spec in package io.
-	//
-	// plus, if the string is really
-	// too big to fit in the buffer, then
-	// creating a copy to write it is
-	// expensive (and, strictly speaking,
-	// unnecessary)
-	if c < ln {
-		return w.w.Write(unsafestr(s))
-	}
 
 	// grow buf slice; copy; return
 	w.buf = w.buf[:l+ln]
@@ -147,8 +535,11 @@ func (w *Writer) WriteString(s string) (int, error) {
 
 // WriteByte implements `io.ByteWriter`
 func (w *Writer) WriteByte(b byte) error {
+	if w.err != nil {
+		return w.err
+	}
 	if len(w.buf) == cap(w.buf) {
-		if err := w.Flush(); err != nil {
+		if err := w.flushFor(1); err != nil {
 			return err
 		}
 	}
@@ -156,6 +547,29 @@ func (w *Writer) WriteByte(b byte) error {
 	return nil
 }
 
+// WriteRune writes the UTF-8 encoding of 'r'
+// and returns the number of bytes written.
+func (w *Writer) WriteRune(r rune) (int, error) {
+	if uint32(r) < utf8.RuneSelf {
+		if err := w.WriteByte(byte(r)); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.Available() < utf8.UTFMax {
+		if err := w.flushFor(utf8.UTFMax); err != nil {
+			return 0, err
+		}
+	}
+	l := len(w.buf)
+	n := utf8.EncodeRune(w.buf[l:l+utf8.UTFMax], r)
+	w.buf = w.buf[:l+n]
+	return n, nil
+}
+
 // Next returns the next 'n' free bytes
 // in the write buffer, flushing the writer
 // as necessary. Next will return `io.ErrShortBuffer`
@@ -163,13 +577,16 @@ func (w *Writer) WriteByte(b byte) error {
 // Calls to 'next' increment the write position by
 // the size of the returned buffer.
 func (w *Writer) Next(n int) ([]byte, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
 	c, l := cap(w.buf), len(w.buf)
 	if n > c {
 		return nil, io.ErrShortBuffer
 	}
 	avail := c - l
 	if avail < n {
-		if err := w.Flush(); err != nil {
+		if err := w.flushFor(n); err != nil {
 			return nil, err
 		}
 		l = len(w.buf)
@@ -178,6 +595,81 @@ func (w *Writer) Next(n int) ([]byte, error) {
 	return w.buf[l:], nil
 }
 
+// NextGrow is like Next, except that if 'n'
+// is greater than the size of the write buffer,
+// the writer is flushed and the buffer is grown
+// to fit 'n' bytes rather than returning
+// [io.ErrShortBuffer]. NextGrow returns
+// [ErrBufferLimit] if the buffer would have to
+// grow past the limit set with [Writer.SetBufferLimit].
+func (w *Writer) NextGrow(n int) ([]byte, error) {
+	if n <= cap(w.buf) {
+		return w.Next(n)
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	if w.maxBuf > 0 && n > w.maxBuf {
+		return nil, ErrBufferLimit
+	}
+	if err := w.flushFor(n); err != nil {
+		return nil, err
+	}
+	l := len(w.buf)
+	w.buf = w.buf[:l+n]
+	return w.buf[l:], nil
+}
+
+// grow makes sure that the buffer
+// has room for 'n' more bytes
+func (w *Writer) grow(n int) error {
+	need := len(w.buf) + n
+	if c := cap(w.buf); need > c {
+		if w.maxBuf > 0 && need > w.maxBuf {
+			return ErrBufferLimit
+		}
+		// grow geometrically, like the Reader
+		size := max(need, 2*c)
+		if w.maxBuf > 0 && size > w.maxBuf {
+			size = w.maxBuf
+		}
+		buf := make([]byte, len(w.buf), size)
+		copy(buf, w.buf)
+		w.buf = buf
+	}
+	return nil
+}
+
+// readFromHeld reads from 'r' into the
+// buffer, for when the buffered data
+// is being held back and can't be bypassed
+func (w *Writer) readFromHeld(r io.Reader) (int64, error) {
+	var nn int64
+	empty := 0 // consecutive empty reads
+	for {
+		if w.Available() == 0 {
+			if err := w.flushFor(1); err != nil {
+				return nn, err
+			}
+		}
+		l := len(w.buf)
+		x, err := r.Read(w.buf[l:cap(w.buf)])
+		w.buf = w.buf[:l+x]
+		nn += int64(x)
+		if err == io.EOF {
+			return nn, nil
+		}
+		if err != nil {
+			return nn, w.wrapErr("ReadFrom", 0, err)
+		}
+		if x > 0 {
+			empty = 0
+		} else if empty++; empty > w.retries {
+			return nn, w.wrapErr("ReadFrom", 0, io.ErrNoProgress)
+		}
+	}
+}
+
 // take the bytes from w.buf[n:len(w.buf)]
 // and put them at the beginning of w.buf,
 // and resize to the length of the copied segment.
@@ -185,52 +677,58 @@ func (w *Writer) pushback(n int) {
 	w.buf = w.buf[:copy(w.buf, w.buf[n:])]
 }
 
-// ReadFrom implements `io.ReaderFrom`
+// ReadFrom implements `io.ReaderFrom`.
+// After flushing the buffer, ReadFrom hands
+// off to the underlying writer's ReadFrom method,
+// if it has one. If 'r' is a [*Reader], its
+// buffered data is written directly to the
+// underlying writer.
 func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(w.holds) > 0 {
+		return w.readFromHeld(r)
+	}
+	if rd, ok := r.(*Reader); ok {
+		return rd.WriteTo(w)
+	}
+
 	// anticipatory flush
 	if err := w.Flush(); err != nil {
 		return 0, err
 	}
+	if rf, ok := w.w.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(r)
+		w.written += n
+		return n, w.wrapErr("ReadFrom", 0, err)
+	}
 
-	w.buf = w.buf[0:cap(w.buf)] // expand buffer
-
-	var nn int64  // written
-	var err error // error
-	var x int     // read
+	var nn int64 // read
+	empty := 0   // consecutive empty reads
 
-	// 1:1 reads and writes
-	for err == nil {
-		x, err = r.Read(w.buf)
+	// 1:1 reads and writes; anything that
+	// can't be written stays buffered
+	for {
+		x, err := r.Read(w.buf[:cap(w.buf)])
+		w.buf = w.buf[:x]
+		nn += int64(x)
 		if x > 0 {
-			n, werr := w.w.Write(w.buf[:x])
-			nn += int64(n)
-
-			if err != nil {
-				if n < x && n > 0 {
-					w.pushback(n - x)
-				}
-				return nn, werr
-			}
-			if n < x {
-				w.pushback(n - x)
-				return nn, io.ErrShortWrite
+			if ferr := w.Flush(); ferr != nil {
+				return nn, ferr
 			}
+			empty = 0
 		} else if err == nil {
-			err = io.ErrNoProgress
-			break
+			if empty >= w.retries {
+				return nn, w.wrapErr("ReadFrom", 0, io.ErrNoProgress)
+			}
+			empty++
+		}
+		if err == io.EOF {
+			return nn, nil
+		}
+		if err != nil {
+			return nn, w.wrapErr("ReadFrom", 0, err)
 		}
 	}
-	if err != io.EOF {
-		return nn, err
-	}
-
-	// we only clear here
-	// because we are sure
-	// the writes have
-	// succeeded. otherwise,
-	// we retain the data in case
-	// future writes succeed.
-	w.buf = w.buf[0:0]
-
-	return nn, nil
 }
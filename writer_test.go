@@ -2,9 +2,14 @@ package fwd
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"math/rand"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 type chunkedWriter struct {
@@ -261,3 +266,490 @@ func TestWriterBufCreation(t *testing.T) {
 		}
 	}
 }
+
+// plainWriter hides any methods
+// other than Write on the underlying writer
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p plainWriter) Write(b []byte) (int, error) { return p.w.Write(b) }
+
+// readFromCounter counts calls to ReadFrom
+type readFromCounter struct {
+	bytes.Buffer
+	calls int
+}
+
+func (r *readFromCounter) ReadFrom(rd io.Reader) (int64, error) {
+	r.calls++
+	return r.Buffer.ReadFrom(rd)
+}
+
+func TestReadFromBuffered(t *testing.T) {
+	nbts := 5000
+	bts := randomBts(nbts)
+
+	// no fast path available
+	var buf bytes.Buffer
+	wr := NewWriterSize(plainWriter{&buf}, 256)
+	wr.Write(bts[:10])
+	nb, err := wr.ReadFrom(partialReader{bytes.NewReader(bts[10:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nb != int64(nbts-10) {
+		t.Fatalf("expected to write %d bytes; wrote %d", nbts-10, nb)
+	}
+	wr.Flush()
+	if !bytes.Equal(buf.Bytes(), bts) {
+		t.Fatal("buf.Bytes() and input are not equal")
+	}
+
+	// underlying writer implements io.ReaderFrom
+	var rfc readFromCounter
+	wr = NewWriterSize(&rfc, 256)
+	wr.Write(bts[:10])
+	if _, err := wr.ReadFrom(partialReader{bytes.NewReader(bts[10:])}); err != nil {
+		t.Fatal(err)
+	}
+	if rfc.calls != 1 {
+		t.Fatalf("expected 1 call to ReadFrom; got %d", rfc.calls)
+	}
+	if !bytes.Equal(rfc.Bytes(), bts) {
+		t.Fatal("buf.Bytes() and input are not equal")
+	}
+
+	// *Reader -> *Writer, with data buffered in both
+	rfc = readFromCounter{}
+	wr = NewWriterSize(&rfc, 256)
+	rd := NewReaderSize(partialReader{bytes.NewReader(bts[10:])}, 128)
+	rd.Peek(100)
+	wr.Write(bts[:10])
+	nb, err = io.Copy(wr, rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nb != int64(nbts-10) {
+		t.Fatalf("expected to copy %d bytes; copied %d", nbts-10, nb)
+	}
+	if rfc.calls != 1 {
+		t.Fatalf("expected 1 call to ReadFrom; got %d", rfc.calls)
+	}
+	if wr.Buffered() != 0 {
+		t.Fatalf("expected 0 buffered bytes; found %d", wr.Buffered())
+	}
+	if !bytes.Equal(rfc.Bytes(), bts) {
+		t.Fatal("buf.Bytes() and input are not equal")
+	}
+	if rd.InputOffset() != int64(nbts-10) {
+		t.Fatalf("expected offset %d; got %d", nbts-10, rd.InputOffset())
+	}
+}
+
+// failWriter fails after 'n' bytes
+type failWriter struct {
+	n      int
+	closed bool
+}
+
+var errFail = errors.New("failWriter: write failed")
+
+func (f *failWriter) Write(p []byte) (int, error) {
+	if len(p) > f.n {
+		n := f.n
+		f.n = 0
+		return n, errFail
+	}
+	f.n -= len(p)
+	return len(p), nil
+}
+
+func (f *failWriter) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestWriterStickyError(t *testing.T) {
+	fw := &failWriter{n: 20}
+	wr := NewWriterSize(fw, 16)
+
+	if _, err := wr.Write(make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wr.Write(make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.Flush(); err != errFail {
+		t.Fatalf("expected %q; got %v", errFail, err)
+	}
+	if wr.Buffered() != 12 {
+		t.Fatalf("expected 12 buffered bytes; found %d", wr.Buffered())
+	}
+	if err := wr.WriteByte(0); err != errFail {
+		t.Fatalf("expected %q; got %v", errFail, err)
+	}
+	if _, err := wr.Next(1); err != errFail {
+		t.Fatalf("expected %q; got %v", errFail, err)
+	}
+	if _, err := wr.WriteString("x"); err != errFail {
+		t.Fatalf("expected %q; got %v", errFail, err)
+	}
+	if n, err := wr.ReadFrom(strings.NewReader("hello")); n != 0 || err != errFail {
+		t.Fatalf("expected 0, %q; got %d, %v", errFail, n, err)
+	}
+	wr.Begin()
+	if n, err := wr.ReadFrom(strings.NewReader("hello")); n != 0 || err != errFail {
+		t.Fatalf("expected 0, %q; got %d, %v", errFail, n, err)
+	}
+	if n, err := wr.ReadFrom(NewReaderBytes([]byte("hello"))); n != 0 || err != errFail {
+		t.Fatalf("expected 0, %q; got %d, %v", errFail, n, err)
+	}
+	if err := wr.Close(); err != errFail {
+		t.Fatalf("expected %q; got %v", errFail, err)
+	}
+	if !fw.closed {
+		t.Fatal("Close did not close the underlying writer")
+	}
+
+	// Reset clears the error and retargets the writer
+	var buf bytes.Buffer
+	wr.Reset(&buf)
+	if wr.Buffered() != 0 {
+		t.Fatalf("expected 0 buffered bytes; found %d", wr.Buffered())
+	}
+	if wr.BufferSize() != 16 {
+		t.Fatalf("expected buffer size 16; found %d", wr.BufferSize())
+	}
+	wr.WriteString("hello")
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "hello" {
+		t.Fatalf("expected %q; got %q", "hello", buf.String())
+	}
+}
+
+func TestWriterAvailable(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 64)
+
+	if wr.Size() != 64 || wr.Available() != 64 {
+		t.Fatalf("expected Size() and Available() to be 64; got %d and %d", wr.Size(), wr.Available())
+	}
+	wr.WriteString("x=")
+	b := strconv.AppendInt(wr.AvailableBuffer(), -12345, 10)
+	if err := wr.Advance(len(b)); err != nil {
+		t.Fatal(err)
+	}
+	if wr.Available() != 64-8 {
+		t.Fatalf("expected Available() to be %d; got %d", 64-8, wr.Available())
+	}
+	if err := wr.Advance(100); err != io.ErrShortBuffer {
+		t.Fatalf("expected %q; got %v", io.ErrShortBuffer, err)
+	}
+
+	// passing the slice to Write works, too
+	b = strconv.AppendQuote(wr.AvailableBuffer(), "y")
+	wr.Write(b)
+
+	str := strings.Repeat("aé€😀", 20)
+	for _, r := range str {
+		n, err := wr.WriteRune(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != utf8.RuneLen(r) {
+			t.Fatalf("expected to write %d bytes; wrote %d", utf8.RuneLen(r), n)
+		}
+	}
+	wr.WriteRune(utf8.MaxRune + 1)
+	wr.Flush()
+	if want := `x=-12345"y"` + str + "�"; buf.String() != want {
+		t.Fatalf("expected %q; got %q", want, buf.String())
+	}
+}
+
+func TestWriterNextGrow(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 64)
+	wr.SetBufferShrink(true)
+	wr.SetBufferLimit(4096)
+
+	bts := randomBts(1000)
+	wr.Write(bts[:10])
+	if _, err := wr.Next(1000); err != io.ErrShortBuffer {
+		t.Fatalf("expected %q; got %v", io.ErrShortBuffer, err)
+	}
+	out, err := wr.NextGrow(990)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 990 {
+		t.Fatalf("expected 990 bytes; got %d", len(out))
+	}
+	copy(out, bts[10:])
+	if wr.BufferSize() < 990 {
+		t.Fatalf("expected buffer to grow; size is %d", wr.BufferSize())
+	}
+	if _, err := wr.NextGrow(5000); err != ErrBufferLimit {
+		t.Fatalf("expected %q; got %v", ErrBufferLimit, err)
+	}
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if wr.BufferSize() != 64 {
+		t.Fatalf("expected buffer to shrink to 64 bytes; size is %d", wr.BufferSize())
+	}
+	if !bytes.Equal(buf.Bytes(), bts) {
+		t.Fatal("buf.Bytes() and input are not equal")
+	}
+}
+
+func TestWriterPad(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 16)
+
+	wr.WriteString("abc")
+	if wr.OutputOffset() != 3 {
+		t.Fatalf("expected offset 3; got %d", wr.OutputOffset())
+	}
+	if err := wr.Pad(8, '.'); err != nil {
+		t.Fatal(err)
+	}
+	if wr.OutputOffset() != 8 {
+		t.Fatalf("expected offset 8; got %d", wr.OutputOffset())
+	}
+	wr.Pad(8, '!') // already aligned
+	wr.WriteByte('x')
+	if err := wr.Pad(100, 0); err != nil {
+		t.Fatal(err)
+	}
+	wr.Write(make([]byte, 50)) // larger than the buffer
+	if wr.OutputOffset() != 150 {
+		t.Fatalf("expected offset 150; got %d", wr.OutputOffset())
+	}
+	wr.Flush()
+	if wr.OutputOffset() != 150 {
+		t.Fatalf("expected offset 150 after Flush; got %d", wr.OutputOffset())
+	}
+	want := "abc.....x" + string(make([]byte, 141))
+	if buf.String() != want {
+		t.Fatalf("expected %q; got %q", want, buf.String())
+	}
+}
+
+func TestWriterReserve(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 16)
+	wr.WriteString("head")
+	res, err := wr.Reserve(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Offset() != 4 || res.Len() != 4 {
+		t.Fatalf("bad reservation at %d of %d bytes", res.Offset(), res.Len())
+	}
+	body := randomBts(100)
+	wr.Write(body)
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "head" {
+		t.Fatalf("flushed %q before the reservation was filled", buf.String())
+	}
+	if err := res.Fill([]byte{1, 2}); err != io.ErrShortBuffer {
+		t.Fatalf("expected io.ErrShortBuffer; got %v", err)
+	}
+	if err := res.Fill([]byte("size")); err != nil {
+		t.Fatal(err)
+	}
+	if err := res.Fill([]byte("size")); err != ErrInvalidReservation {
+		t.Fatalf("expected ErrInvalidReservation; got %v", err)
+	}
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte("headsize"), body...)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatal("output not equal")
+	}
+
+	// reservations don't have to be
+	// filled in order
+	buf.Reset()
+	wr.Reset(&buf)
+	first, _ := wr.Reserve(1)
+	second, _ := wr.Reserve(1)
+	wr.WriteString("x")
+	second.Fill([]byte("2"))
+	wr.Flush()
+	if buf.Len() != 0 {
+		t.Fatalf("flushed %q", buf.String())
+	}
+	first.Fill([]byte("1"))
+	wr.Flush()
+	if buf.String() != "12x" {
+		t.Fatalf("got %q", buf.String())
+	}
+
+	// the buffer limit still applies
+	wr = NewWriterSize(io.Discard, 16)
+	wr.SetBufferLimit(32)
+	wr.Reserve(8)
+	if _, err := wr.Write(make([]byte, 100)); err != ErrBufferLimit {
+		t.Fatalf("expected ErrBufferLimit; got %v", err)
+	}
+}
+
+func TestWriterTransaction(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriterSize(&buf, 16)
+	wr.WriteString("one;")
+	sp := wr.Begin()
+	wr.Write(randomBts(100))
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "one;" {
+		t.Fatalf("flushed %q", buf.String())
+	}
+	res, _ := wr.Reserve(2)
+	inner := wr.Begin()
+	wr.WriteString("inner")
+	if err := wr.Rollback(sp); err != nil {
+		t.Fatal(err)
+	}
+	if wr.OutputOffset() != 4 {
+		t.Fatalf("output offset is %d after rollback", wr.OutputOffset())
+	}
+	if err := res.Fill([]byte("xx")); err != ErrInvalidReservation {
+		t.Fatalf("expected ErrInvalidReservation; got %v", err)
+	}
+	if err := wr.Commit(inner); err != ErrInvalidSavepoint {
+		t.Fatalf("expected ErrInvalidSavepoint; got %v", err)
+	}
+	if err := wr.Rollback(sp); err != ErrInvalidSavepoint {
+		t.Fatalf("expected ErrInvalidSavepoint; got %v", err)
+	}
+
+	sp = wr.Begin()
+	wr.WriteString("two;")
+	inner = wr.Begin()
+	wr.WriteString("dropped;")
+	wr.Rollback(inner)
+	wr.WriteString("three;")
+	wr.Flush()
+	if buf.String() != "one;" {
+		t.Fatalf("flushed %q", buf.String())
+	}
+	if err := wr.Commit(sp); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "one;two;three;" {
+		t.Fatalf("got %q", buf.String())
+	}
+
+	// rolling back drops holds begun
+	// after it at the same offset, too
+	buf.Reset()
+	wr.Reset(&buf)
+	sp = wr.Begin()
+	inner = wr.Begin()
+	wr.Reserve(2)
+	if err := wr.Rollback(sp); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.Commit(inner); err != ErrInvalidSavepoint {
+		t.Fatalf("expected ErrInvalidSavepoint; got %v", err)
+	}
+	wr.WriteString("four;")
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "four;" {
+		t.Fatalf("got %q", buf.String())
+	}
+
+	// savepoints don't survive a Reset
+	sp = wr.Begin()
+	wr.Reset(&buf)
+	wr.Begin()
+	if err := wr.Commit(sp); err != ErrInvalidSavepoint {
+		t.Fatalf("expected ErrInvalidSavepoint; got %v", err)
+	}
+}
+
+// flakyWriter writes part of every other
+// write and then times out
+type flakyWriter struct {
+	w     io.Writer
+	calls int
+}
+
+func (f *flakyWriter) Write(p []byte) (int, error) {
+	f.calls++
+	if f.calls%2 == 0 {
+		return f.w.Write(p)
+	}
+	n, _ := f.w.Write(p[:len(p)/2])
+	return n, os.ErrDeadlineExceeded
+}
+
+func TestWriterResume(t *testing.T) {
+	data := randomBts(5000)
+	var buf bytes.Buffer
+	wr := NewWriterSize(&flakyWriter{w: &buf}, 64)
+	for i := 0; i < len(data); i += 100 {
+		p := data[i : i+100]
+		for len(p) > 0 {
+			n, err := wr.Write(p)
+			if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Fatal(err)
+			}
+			p = p[n:]
+		}
+	}
+	for {
+		err := wr.Flush()
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("data lost or duplicated across timeouts")
+	}
+
+	// ReadFrom keeps what it couldn't write
+	buf.Reset()
+	wr = NewWriterSize(&flakyWriter{w: &buf}, 64)
+	src := partialReader{bytes.NewReader(data)}
+	var nn int64
+	for {
+		n, err := wr.ReadFrom(src)
+		nn += n
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatal(err)
+		}
+	}
+	if nn != int64(len(data)) {
+		t.Fatalf("ReadFrom read %d bytes", nn)
+	}
+	for wr.Flush() != nil {
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("data lost or duplicated across timeouts")
+	}
+	if wr.OutputOffset() != int64(len(data)) {
+		t.Fatalf("output offset %d", wr.OutputOffset())
+	}
+}